@@ -0,0 +1,41 @@
+package main
+
+// Counter counts the data written to it, so input can be fed in pieces as
+// it arrives. Words and characters split between writes are counted once.
+type Counter struct {
+	Counts
+
+	inWord bool
+}
+
+func (c *Counter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		c.Bytes++
+
+		// Continuation bytes belong to the rune started before them.
+		if b&0xC0 != 0x80 {
+			c.Chars++
+		}
+
+		switch {
+		case b == '\n':
+			c.Lines++
+			c.inWord = false
+		case isSpace(b):
+			c.inWord = false
+		default:
+			if !c.inWord {
+				c.Words++
+			}
+			c.inWord = true
+		}
+	}
+
+	return len(p), nil
+}
+
+// Break ends the current word, for when the next write comes from a
+// different input.
+func (c *Counter) Break() {
+	c.inWord = false
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrFollowArgs      = errors.New("Follow mode takes exactly one file")
+	ErrInvalidInterval = errors.New("Report interval must be positive")
+)
+
+// pollInterval is how often a followed file is checked for new data.
+var pollInterval = 250 * time.Millisecond
+
+// follower keeps counting a file as it grows, like tail -f. When the file
+// is truncated it starts over from the beginning, and when it is replaced,
+// as log rotation does, it switches to the new file. Totals keep running
+// across both.
+type follower struct {
+	name string
+	file *os.File
+	info fs.FileInfo
+
+	offset int64
+	c      Counter
+
+	last   Counts
+	lastAt time.Time
+}
+
+func newFollower(name string, now time.Time) (*follower, error) {
+	fl := &follower{name: name, lastAt: now}
+
+	if err := fl.open(); err != nil {
+		return nil, err
+	}
+
+	if err := fl.read(); err != nil {
+		fl.Close()
+		return nil, err
+	}
+
+	fl.last = fl.c.Counts
+
+	return fl, nil
+}
+
+func (fl *follower) open() error {
+	f, err := os.Open(fl.name)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	fl.file, fl.info, fl.offset = f, info, 0
+	fl.c.Break()
+
+	return nil
+}
+
+func (fl *follower) read() error {
+	n, err := io.Copy(&fl.c, fl.file)
+	fl.offset += n
+
+	return err
+}
+
+// poll counts the data added since the last poll, following truncation
+// and rotation of the file.
+func (fl *follower) poll() error {
+	if err := fl.read(); err != nil {
+		return err
+	}
+
+	info, err := fl.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() < fl.offset {
+		if _, err := fl.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		fl.offset = 0
+		fl.c.Break()
+
+		return fl.read()
+	}
+
+	current, err := os.Stat(fl.name)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Rotated away and not yet recreated.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if os.SameFile(current, fl.info) {
+		return nil
+	}
+
+	if err := fl.file.Close(); err != nil {
+		return err
+	}
+
+	if err := fl.open(); err != nil {
+		return err
+	}
+
+	return fl.read()
+}
+
+// report prints the running totals selected by flags and their rate per
+// second since the previous report.
+func (fl *follower) report(w io.Writer, flags int, now time.Time) error {
+	elapsed := now.Sub(fl.lastAt).Seconds()
+
+	columns := []struct {
+		flag  int
+		name  string
+		total int
+		last  int
+	}{
+		{CountLines, "lines", fl.c.Lines, fl.last.Lines},
+		{CountWords, "words", fl.c.Words, fl.last.Words},
+		{CountChars, "chars", fl.c.Chars, fl.last.Chars},
+		{CountBytes, "bytes", fl.c.Bytes, fl.last.Bytes},
+	}
+
+	fields := []string{now.Format(time.TimeOnly)}
+
+	for _, col := range columns {
+		if flags&col.flag == 0 {
+			continue
+		}
+
+		rate := 0.0
+		if elapsed > 0 {
+			rate = float64(col.total-col.last) / elapsed
+		}
+
+		fields = append(fields, fmt.Sprintf("%s=%d (%.1f/s)", col.name, col.total, rate))
+	}
+
+	fl.last, fl.lastAt = fl.c.Counts, now
+
+	_, err := fmt.Fprintln(w, strings.Join(fields, " "))
+	return err
+}
+
+func (fl *follower) Close() error {
+	return fl.file.Close()
+}
+
+// follow counts the file until ctx is done, reporting every interval and
+// once more when it stops.
+func follow(ctx context.Context, args []string, cfg config, out io.Writer) error {
+	if len(args) != 1 || args[0] == "-" {
+		return ErrFollowArgs
+	}
+
+	if cfg.interval <= 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidInterval, cfg.interval)
+	}
+
+	if cfg.flags == 0 {
+		cfg.flags = CountDefault
+	}
+
+	fl, err := newFollower(args[0], time.Now())
+	if err != nil {
+		return err
+	}
+	defer fl.Close()
+
+	pollTicker := time.NewTicker(min(pollInterval, cfg.interval))
+	defer pollTicker.Stop()
+
+	reportTicker := time.NewTicker(cfg.interval)
+	defer reportTicker.Stop()
+
+	for {
+		select {
+		case <-pollTicker.C:
+			if err := fl.poll(); err != nil {
+				return err
+			}
+
+		case now := <-reportTicker.C:
+			if err := fl.poll(); err != nil {
+				return err
+			}
+
+			if err := fl.report(out, cfg.flags, now); err != nil {
+				return err
+			}
+
+		case <-ctx.Done():
+			if err := fl.poll(); err != nil {
+				return err
+			}
+
+			return fl.report(out, cfg.flags, time.Now())
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCounterWrites(t *testing.T) {
+	data := []byte("héllo wörld\n日本語 again\n")
+
+	exp, err := count(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Feed one byte at a time, splitting every word and rune.
+	c := &Counter{}
+	for i := range data {
+		c.Write(data[i : i+1])
+	}
+
+	if c.Counts != exp {
+		t.Errorf("Expected %+v, got %+v instead.", exp, c.Counts)
+	}
+}
+
+func appendFile(t *testing.T, name, data string) {
+	t.Helper()
+
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollower(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "app.log")
+
+	appendFile(t, fname, "one two\n")
+
+	start := time.Now()
+	fl, err := newFollower(fname, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	steps := []struct {
+		name string
+		fn   func()
+		exp  Counts
+	}{
+		{"Initial", func() {}, Counts{Lines: 1, Words: 2, Chars: 8, Bytes: 8}},
+		{"Append", func() { appendFile(t, fname, "three\n") }, Counts{Lines: 2, Words: 3, Chars: 14, Bytes: 14}},
+		{"Truncate", func() {
+			if err := os.Truncate(fname, 0); err != nil {
+				t.Fatal(err)
+			}
+			appendFile(t, fname, "x\n")
+		}, Counts{Lines: 3, Words: 4, Chars: 16, Bytes: 16}},
+		{"Rotate", func() {
+			appendFile(t, fname, "last\n")
+			if err := os.Rename(fname, fname+".1"); err != nil {
+				t.Fatal(err)
+			}
+		}, Counts{Lines: 4, Words: 5, Chars: 21, Bytes: 21}},
+		{"Recreate", func() { appendFile(t, fname, "new file\n") }, Counts{Lines: 5, Words: 7, Chars: 30, Bytes: 30}},
+	}
+
+	for _, s := range steps {
+		t.Run(s.name, func(t *testing.T) {
+			s.fn()
+
+			if err := fl.poll(); err != nil {
+				t.Fatal(err)
+			}
+
+			if fl.c.Counts != s.exp {
+				t.Errorf("Expected %+v, got %+v instead.", s.exp, fl.c.Counts)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	if err := fl.report(&out, CountLines, start.Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(out.String(), " lines=5 (2.0/s)\n") {
+		t.Errorf("Unexpected report %q", out.String())
+	}
+}
+
+func TestFollow(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, fname, "a b c\n")
+
+	cfg := config{flags: CountLines | CountWords, interval: 20 * time.Millisecond}
+
+	if err := follow(context.Background(), []string{"a", "b"}, cfg, &bytes.Buffer{}); !errors.Is(err, ErrFollowArgs) {
+		t.Errorf("Expected error %q, got %v instead.", ErrFollowArgs, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		appendFile(t, fname, "d e\n")
+	}()
+
+	var out bytes.Buffer
+	if err := follow(ctx, []string{fname}, cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("Expected periodic reports, got %q", out.String())
+	}
+
+	if last := lines[len(lines)-1]; !strings.Contains(last, "lines=2 ") || !strings.Contains(last, "words=5 ") {
+		t.Errorf("Expected final totals, got %q instead.", last)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// globs collects the patterns given to a repeatable, comma separated flag.
+type globs []string
+
+func (g *globs) String() string {
+	return strings.Join(*g, ",")
+}
+
+func (g *globs) Set(v string) error {
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("%w: %q", err, p)
+		}
+
+		*g = append(*g, p)
+	}
+
+	return nil
+}
+
+func (g globs) match(name string) bool {
+	for _, p := range g {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keep reports whether a file found in a directory should be counted. The
+// patterns match the base name of the file; directories are excluded by
+// expand.
+func (cfg config) keep(path string) bool {
+	name := filepath.Base(path)
+
+	if len(cfg.include) > 0 && !cfg.include.match(name) {
+		return false
+	}
+
+	return !cfg.exclude.match(name)
+}
+
+// expand replaces the directories among args with the regular files under
+// them that cfg keeps, in lexical order, skipping the directories whose name
+// cfg excludes. Files given explicitly are always kept. Paths that cannot be
+// read are returned as errors.
+func expand(args []string, cfg config) ([]string, []error) {
+	files := []string{}
+	errs := []error{}
+
+	for _, arg := range args {
+		if arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+
+			if d.IsDir() && path != arg && cfg.exclude.match(d.Name()) {
+				return fs.SkipDir
+			}
+
+			if d.Type().IsRegular() && cfg.keep(path) {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return files, errs
+}
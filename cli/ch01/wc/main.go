@@ -1,51 +1,244 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"time"
 )
 
 const (
 	CountLines = 1 << iota
 	CountBytes
+	CountWords
+	CountChars
+
+	// CountDefault is what GNU wc prints when no count is asked for.
+	CountDefault = CountLines | CountWords | CountBytes
 )
 
+var ErrCount = errors.New("Cannot count some inputs")
+
+// Counts holds the totals of one input. Lines are newline characters,
+// words are runs of non whitespace bytes and chars are UTF-8 encoded runes.
+type Counts struct {
+	Lines int `json:"lines"`
+	Words int `json:"words"`
+	Chars int `json:"chars"`
+	Bytes int `json:"bytes"`
+}
+
+func (c *Counts) Add(o Counts) {
+	c.Lines += o.Lines
+	c.Words += o.Words
+	c.Chars += o.Chars
+	c.Bytes += o.Bytes
+}
+
+type config struct {
+	flags   int
+	json    bool
+	include globs
+	exclude globs
+	workers int
+
+	follow   bool
+	interval time.Duration
+}
+
 func main() {
 	lines := flag.Bool("l", false, "Count lines")
-	bytes := flag.Bool("b", false, "Bytes lines")
+	bytes := flag.Bool("c", false, "Count bytes")
+	flag.BoolVar(bytes, "b", false, "Count bytes, same as -c")
+	words := flag.Bool("w", false, "Count words")
+	chars := flag.Bool("m", false, "Count UTF-8 characters")
+	jsonOut := flag.Bool("json", false, "Print the counts as JSON")
+	followFile := flag.Bool("f", false, "Follow a growing file, reporting totals and rates")
+	interval := flag.Duration("interval", 5*time.Second, "How often to report in follow mode")
+
+	cfg := config{workers: runtime.NumCPU()}
+	flag.Var(&cfg.include, "include", "Only count files whose name matches these globs")
+	flag.Var(&cfg.exclude, "exclude", "Skip files and directories whose name matches these globs")
 
 	flag.Parse()
 
-	flags := 0
 	if *lines {
-		flags |= CountLines
+		cfg.flags |= CountLines
+	}
+	if *words {
+		cfg.flags |= CountWords
+	}
+	if *chars {
+		cfg.flags |= CountChars
 	}
 	if *bytes {
-		flags |= CountBytes
+		cfg.flags |= CountBytes
 	}
+	cfg.json = *jsonOut
+	cfg.follow = *followFile
+	cfg.interval = *interval
 
-	fmt.Println(count(os.Stdin, flags))
+	if cfg.follow {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if err := follow(ctx, flag.Args(), cfg, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if err := run(flag.Args(), cfg, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+type result struct {
+	Name string `json:"name"`
+	Counts
 }
 
-func count(r io.Reader, flags int) int {
-	scanner := bufio.NewScanner(r)
+// run prints the counts selected by cfg for every file, reading STDIN when
+// no files or "-" are given, followed by a total row for more than one
+// file. Directories are counted recursively. Files that cannot be read are
+// reported to errOut.
+func run(args []string, cfg config, out, errOut io.Writer) error {
+	if cfg.flags == 0 {
+		cfg.flags = CountDefault
+	}
+
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+
+	files, errs := expand(args, cfg)
+	for _, err := range errs {
+		fmt.Fprintf(errOut, "wc: %v\n", err)
+	}
+
+	failed := len(errs) > 0
+	counts, errs := countFiles(files, cfg.workers)
+
+	results := []result{}
+	total := Counts{}
+
+	for idx, fname := range files {
+		if errs[idx] != nil {
+			fmt.Fprintf(errOut, "wc: %s: %v\n", fname, errs[idx])
+			failed = true
+			continue
+		}
+
+		results = append(results, result{fname, counts[idx]})
+		total.Add(counts[idx])
+	}
 
-	if flags&CountLines != 0 {
-		scanner.Split(bufio.ScanLines)
-	} else if flags&CountBytes != 0 {
-		scanner.Split(bufio.ScanBytes)
-	} else {
-		scanner.Split(bufio.ScanWords)
+	if cfg.json {
+		if err := printJSON(out, results, total); err != nil {
+			return err
+		}
+	} else if err := printTable(out, results, total, cfg.flags, len(args) > 1 || len(files) > 1); err != nil {
+		return err
 	}
 
-	wc := 0
+	if failed {
+		return ErrCount
+	}
+
+	return nil
+}
+
+// count reads r once, counting lines, words, characters and bytes at the
+// same time.
+func count(r io.Reader) (Counts, error) {
+	c := &Counter{}
+
+	_, err := io.Copy(c, r)
+
+	return c.Counts, err
+}
+
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+
+	return false
+}
+
+// printTable prints a row per result, padding the columns to the widest
+// count, followed by the total when withTotal is set.
+func printTable(w io.Writer, results []result, total Counts, flags int, withTotal bool) error {
+	if withTotal {
+		results = append(results, result{"total", total})
+	}
+
+	width := len(strconv.Itoa(max(total.Lines, total.Words, total.Chars, total.Bytes)))
+
+	for _, r := range results {
+		name := r.Name
+		if name == "-" && !withTotal {
+			name = ""
+		}
+
+		if err := printCounts(w, r.Counts, flags, width, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printJSON(w io.Writer, results []result, total Counts) error {
+	doc := struct {
+		Files []result `json:"files"`
+		Total Counts   `json:"total"`
+	}{results, total}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(doc)
+}
+
+func printCounts(w io.Writer, c Counts, flags, width int, name string) error {
+	columns := []struct {
+		flag  int
+		value int
+	}{
+		{CountLines, c.Lines},
+		{CountWords, c.Words},
+		{CountChars, c.Chars},
+		{CountBytes, c.Bytes},
+	}
+
+	line := ""
+	for _, col := range columns {
+		if flags&col.flag == 0 {
+			continue
+		}
+
+		if line != "" {
+			line += " "
+		}
+		line += fmt.Sprintf("%*d", width, col.value)
+	}
 
-	for scanner.Scan() {
-		wc++
+	if name != "" {
+		line += " " + name
 	}
 
-	return wc
+	_, err := fmt.Fprintln(w, line)
+	return err
 }
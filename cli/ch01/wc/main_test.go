@@ -2,6 +2,10 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -9,21 +13,29 @@ func TestCountWords(t *testing.T) {
 	b := bytes.NewBufferString("word1 word2 word3 word4\n")
 
 	exp := 4
-	res := count(b, 0)
+	res, err := count(b)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if res != exp {
-		t.Errorf("Expected %d, got %d instead.\n", exp, res)
+	if res.Words != exp {
+		t.Errorf("Expected %d, got %d instead.\n", exp, res.Words)
 	}
 }
 
 func TestCountLines(t *testing.T) {
 	b := bytes.NewBufferString("word1 word2 word3\nline2\nline3 word1")
 
-	exp := 3
-	res := count(b, CountLines)
+	// Like GNU wc, lines are newline characters so the last unterminated
+	// line is not counted.
+	exp := 2
+	res, err := count(b)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if res != exp {
-		t.Errorf("Expected %d, got %d instead.\n", exp, res)
+	if res.Lines != exp {
+		t.Errorf("Expected %d, got %d instead.\n", exp, res.Lines)
 	}
 }
 
@@ -31,9 +43,138 @@ func TestCountBytes(t *testing.T) {
 	b := bytes.NewBufferString("word1 word2 word3\nline2\nline3 word1")
 
 	exp := 35
-	res := count(b, CountBytes)
+	res, err := count(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Bytes != exp {
+		t.Errorf("Expected %d, got %d instead.\n", exp, res.Bytes)
+	}
+}
+
+func TestCountChars(t *testing.T) {
+	b := bytes.NewBufferString("héllo wörld\n日本語\n")
+
+	exp := Counts{Lines: 2, Words: 3, Chars: 16, Bytes: 24}
+	res, err := count(b)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if res != exp {
-		t.Errorf("Expected %d, got %d instead.\n", exp, res)
+		t.Errorf("Expected %+v, got %+v instead.\n", exp, res)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	f1 := filepath.Join(dir, "one.txt")
+	f2 := filepath.Join(dir, "two.txt")
+
+	if err := os.WriteFile(f1, []byte("one two\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(f2, []byte("four\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name   string
+		files  []string
+		flags  int
+		exp    string
+		expErr error
+	}{
+		{"SingleFile", []string{f1}, 0, " 2  3 14 " + f1 + "\n", nil},
+		{"Total", []string{f1, f2}, 0,
+			" 2  3 14 " + f1 + "\n 1  1  5 " + f2 + "\n 3  4 19 total\n", nil},
+		{"Chars", []string{f1}, CountLines | CountChars, " 2 14 " + f1 + "\n", nil},
+		{"LinesOnly", []string{f1, f2}, CountLines,
+			" 2 " + f1 + "\n 1 " + f2 + "\n 3 total\n", nil},
+		{"MissingFile", []string{f2, filepath.Join(dir, "missing")}, CountWords | CountBytes,
+			"1 5 " + f2 + "\n1 5 total\n", ErrCount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+
+			err := run(tc.files, config{flags: tc.flags, workers: 2}, &out, &errOut)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("Expected error %v, got %v instead.", tc.expErr, err)
+			}
+
+			if tc.expErr != nil && errOut.Len() == 0 {
+				t.Error("Expected the error to be reported")
+			}
+
+			if out.String() != tc.exp {
+				t.Errorf("Expected %q, got %q instead.", tc.exp, out.String())
+			}
+		})
+	}
+}
+
+func TestRunDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"a.log":          "one\n",
+		"b.txt":          "two words\n",
+		"sub/c.log":      "three\nfour\n",
+		"sub/debug.log":  "skipped\n",
+		"sub/deep/d.log": "five six seven\n",
+		".git/x.log":     "excluded directory\n",
+		"sub/.git/y.log": "excluded directory\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config{flags: CountLines | CountWords, workers: 4, json: true}
+	cfg.include.Set("*.log")
+	cfg.exclude.Set("debug*,.git")
+
+	var out, errOut bytes.Buffer
+	if err := run([]string{dir}, cfg, &out, &errOut); err != nil {
+		t.Fatal(err, errOut.String())
+	}
+
+	var res struct {
+		Files []result
+		Total Counts
+	}
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	expFiles := []string{
+		filepath.Join(dir, "a.log"),
+		filepath.Join(dir, "sub", "c.log"),
+		filepath.Join(dir, "sub", "deep", "d.log"),
+	}
+
+	if len(res.Files) != len(expFiles) {
+		t.Fatalf("Expected %d files, got %+v instead.", len(expFiles), res.Files)
+	}
+
+	for i, exp := range expFiles {
+		if res.Files[i].Name != exp {
+			t.Errorf("Expected file %q, got %q instead.", exp, res.Files[i].Name)
+		}
+	}
+
+	exp := Counts{Lines: 4, Words: 6, Chars: 30, Bytes: 30}
+	if res.Total != exp {
+		t.Errorf("Expected total %+v, got %+v instead.", exp, res.Total)
 	}
 }
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// chunkSize is the size above which files are split into chunks counted by
+// different workers.
+var chunkSize int64 = 16 << 20
+
+// job counts size bytes of a file from offset. A negative size reads until
+// the end of the file.
+type job struct {
+	idx    int
+	name   string
+	offset int64
+	size   int64
+}
+
+type jobResult struct {
+	idx    int
+	counts Counts
+	err    error
+}
+
+// countFiles counts the files using a pool of workers, splitting large
+// files into chunks. It returns the counts and the error, if any, of each
+// file in the same order as names.
+func countFiles(names []string, workers int) ([]Counts, []error) {
+	counts := make([]Counts, len(names))
+	errs := make([]error, len(names))
+
+	jobsCh := make(chan job)
+	resCh := make(chan jobResult)
+
+	go func() {
+		defer close(jobsCh)
+
+		for idx, name := range names {
+			jobs, err := split(idx, name)
+			if err != nil {
+				resCh <- jobResult{idx: idx, err: err}
+				continue
+			}
+
+			for _, j := range jobs {
+				jobsCh <- j
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < max(workers, 1); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range jobsCh {
+				c, err := j.count()
+				resCh <- jobResult{idx: j.idx, counts: c, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(resCh)
+	}()
+
+	for res := range resCh {
+		counts[res.idx].Add(res.counts)
+
+		if res.err != nil && errs[res.idx] == nil {
+			errs[res.idx] = res.err
+		}
+	}
+
+	return counts, errs
+}
+
+func (j job) count() (Counts, error) {
+	if j.name == "-" {
+		return count(os.Stdin)
+	}
+
+	f, err := os.Open(j.name)
+	if err != nil {
+		return Counts{}, err
+	}
+	defer f.Close()
+
+	if j.size < 0 {
+		return count(f)
+	}
+
+	return count(io.NewSectionReader(f, j.offset, j.size))
+}
+
+// split returns the jobs counting a file, one per chunk for files larger
+// than chunkSize.
+func split(idx int, name string) ([]job, error) {
+	whole := []job{{idx: idx, name: name, size: -1}}
+
+	if name == "-" {
+		return whole, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.Mode().IsRegular() || info.Size() <= chunkSize {
+		return whole, nil
+	}
+
+	bounds, err := boundaries(f, info.Size(), chunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []job{}
+	for i := 0; i < len(bounds)-1; i++ {
+		jobs = append(jobs, job{idx: idx, name: name, offset: bounds[i], size: bounds[i+1] - bounds[i]})
+	}
+
+	return jobs, nil
+}
+
+// boundaries returns the offsets splitting a file of the given size into
+// chunks of about chunk bytes, from 0 to size. Every chunk but the first
+// starts right after a whitespace byte, so no word or UTF-8 character is
+// split between two chunks.
+func boundaries(r io.ReaderAt, size, chunk int64) ([]int64, error) {
+	bounds := []int64{0}
+
+	for next := chunk; next < size; {
+		b, err := nextBoundary(r, next)
+		if err != nil {
+			return nil, err
+		}
+
+		if b >= size {
+			break
+		}
+
+		bounds = append(bounds, b)
+		next = b + chunk
+	}
+
+	return append(bounds, size), nil
+}
+
+// nextBoundary returns the offset following the first whitespace byte at
+// or after offset, or the end of r.
+func nextBoundary(r io.ReaderAt, offset int64) (int64, error) {
+	buf := make([]byte, 4096)
+
+	for {
+		n, err := r.ReadAt(buf, offset)
+
+		for i, b := range buf[:n] {
+			if isSpace(b) {
+				return offset + int64(i) + 1, nil
+			}
+		}
+
+		offset += int64(n)
+
+		if errors.Is(err, io.EOF) {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
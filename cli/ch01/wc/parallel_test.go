@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBoundaries(t *testing.T) {
+	data := []byte("aaaa bbbb cccccccccc dd\neeee")
+
+	bounds, err := boundaries(bytes.NewReader(data), int64(len(data)), 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []int64{0, 10, 21, 28}
+	if len(bounds) != len(exp) {
+		t.Fatalf("Expected %v, got %v instead.", exp, bounds)
+	}
+
+	for i := range exp {
+		if bounds[i] != exp[i] {
+			t.Errorf("Expected %v, got %v instead.", exp, bounds)
+			break
+		}
+	}
+}
+
+func TestCountFilesChunks(t *testing.T) {
+	defer func(size int64) { chunkSize = size }(chunkSize)
+	chunkSize = 64
+
+	fname := filepath.Join(t.TempDir(), "big.txt")
+	content := strings.Repeat("héllo wörld 日本語\nanother  line\twith tabs\n", 50)
+
+	if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exp, err := count(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := split(0, fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobs) < 2 {
+		t.Fatalf("Expected the file to be split, got %d jobs", len(jobs))
+	}
+
+	counts, errs := countFiles([]string{fname, fname}, 4)
+
+	for i := range counts {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+
+		if counts[i] != exp {
+			t.Errorf("Expected %+v, got %+v instead.", exp, counts[i])
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"context"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash"
+	"github.com/mum4k/termdash/terminal/tcell"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+)
+
+type App struct {
+	ctx        context.Context
+	controller *termdash.Controller
+	term       *tcell.Terminal
+	size       image.Point
+
+	model *model
+	wid   *widgets
+	sync.Mutex
+
+	redrawCh chan bool
+}
+
+// New builds a full screen UI over the list stored in repo. Changes are
+// saved through update as soon as they are made.
+func New(repo todo.Repository, update UpdateFunc, title string) (*App, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m, err := newModel(repo, update)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	wid, err := newWidgets()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	term, err := tcell.New()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	a := &App{
+		ctx:   ctx,
+		term:  term,
+		model: m,
+		wid:   wid,
+
+		redrawCh: make(chan bool),
+	}
+
+	keys := func(k *terminalapi.Keyboard) {
+		a.Lock()
+		quit := m.handleKey(k.Key)
+		a.Unlock()
+
+		if quit {
+			cancel()
+			return
+		}
+
+		a.redrawCh <- true
+	}
+
+	container, err := newGrid(wid, "Todo: "+title, term)
+	if err != nil {
+		term.Close()
+		cancel()
+		return nil, err
+	}
+
+	if err := wid.update(m, listRows(term.Size().Y)); err != nil {
+		term.Close()
+		cancel()
+		return nil, err
+	}
+
+	a.controller, err = termdash.NewController(
+		term,
+		container,
+		termdash.KeyboardSubscriber(keys),
+	)
+	if err != nil {
+		term.Close()
+		cancel()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *App) Run() error {
+	defer a.term.Close()
+	defer a.controller.Close()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.redrawCh:
+			if err := a.draw(); err != nil {
+				return err
+			}
+
+		case <-ticker.C:
+			if err := a.resize(); err != nil {
+				return err
+			}
+
+		case <-a.ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (a *App) resize() error {
+	if a.size.Eq(a.term.Size()) {
+		return nil
+	}
+
+	a.size = a.term.Size()
+	if err := a.term.Clear(); err != nil {
+		return err
+	}
+
+	return a.draw()
+}
+
+func (a *App) draw() error {
+	a.Lock()
+	err := a.wid.update(a.model, listRows(a.term.Size().Y))
+	a.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return a.controller.Redraw()
+}
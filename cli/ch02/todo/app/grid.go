@@ -0,0 +1,37 @@
+package app
+
+import (
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+const (
+	statusRows = 2
+	borderRows = 2
+)
+
+// newGrid lays the list over a status bar of fixed height, so the list
+// takes whatever the terminal has left.
+func newGrid(wid *widgets, title string, term terminalapi.Terminal) (*container.Container, error) {
+	return container.New(term,
+		container.SplitHorizontal(
+			container.Top(
+				container.Border(linestyle.Light),
+				container.BorderTitle(title),
+				container.PlaceWidget(wid.txtList),
+			),
+			container.Bottom(
+				container.Border(linestyle.Light),
+				container.PlaceWidget(wid.txtStatus),
+			),
+			container.SplitFixedFromEnd(statusRows+borderRows),
+		),
+	)
+}
+
+// listRows returns how many items fit in the list on a terminal of the
+// given height.
+func listRows(height int) int {
+	return max(height-statusRows-2*borderRows, 1)
+}
@@ -0,0 +1,304 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mum4k/termdash/keyboard"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+)
+
+// UpdateFunc applies fn to the stored list and saves it, recording the
+// change as op. fn returns the ID of the item it changed.
+type UpdateFunc func(op todo.Op, fn func(*todo.List) (int, error)) error
+
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeAdd
+	modeAddSubtask
+	modeEdit
+)
+
+const help = "↑/↓ move  space done  a add  s subtask  e edit  d delete  p pending  r reload  q quit"
+
+// model holds the state of the UI and turns key presses into changes to
+// the list. Every change is saved as soon as it is made.
+type model struct {
+	repo   todo.Repository
+	update UpdateFunc
+
+	list     todo.List
+	view     todo.List
+	selected int
+	offset   int
+	pending  bool
+
+	mode   mode
+	input  []rune
+	status string
+}
+
+func newModel(repo todo.Repository, update UpdateFunc) (*model, error) {
+	m := &model{
+		repo:   repo,
+		update: update,
+	}
+
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *model) reload() error {
+	l := todo.List{}
+	if err := m.repo.Get(&l); err != nil {
+		return err
+	}
+
+	m.list = l
+	m.refresh()
+
+	return nil
+}
+
+func (m *model) refresh() {
+	filters := []todo.Filter{}
+	if m.pending {
+		filters = append(filters, todo.IsPending)
+	}
+
+	m.view = m.list.Filter(filters...).Tree()
+	m.selected = max(min(m.selected, len(m.view)-1), 0)
+}
+
+// current returns the ID of the selected item.
+func (m *model) current() (int, bool) {
+	if len(m.view) == 0 {
+		return 0, false
+	}
+
+	return m.view[m.selected].ID, true
+}
+
+func (m *model) selectID(id int) {
+	for idx, t := range m.view {
+		if t.ID == id {
+			m.selected = idx
+			return
+		}
+	}
+}
+
+// handleKey reacts to a key press and reports whether the UI should quit.
+func (m *model) handleKey(k keyboard.Key) bool {
+	if m.mode != modeBrowse {
+		m.handleInput(k)
+		return false
+	}
+
+	m.status = ""
+
+	switch k {
+	case 'q', 'Q':
+		return true
+	case keyboard.KeyArrowUp, 'k':
+		m.selected = max(m.selected-1, 0)
+	case keyboard.KeyArrowDown, 'j':
+		m.selected = max(min(m.selected+1, len(m.view)-1), 0)
+	case keyboard.KeyHome, 'g':
+		m.selected = 0
+	case keyboard.KeyEnd, 'G':
+		m.selected = max(len(m.view)-1, 0)
+	case keyboard.KeySpace, keyboard.KeyEnter:
+		m.toggle()
+	case 'a':
+		m.startInput(modeAdd, "")
+	case 's':
+		if id, ok := m.current(); ok {
+			m.startInput(modeAddSubtask, "")
+			m.status = fmt.Sprintf("Subtask of %d", id)
+		}
+	case 'e':
+		if _, ok := m.current(); ok {
+			m.startInput(modeEdit, m.view[m.selected].Task)
+		}
+	case 'd', keyboard.KeyDelete:
+		m.delete()
+	case 'p':
+		m.pending = !m.pending
+		m.refresh()
+	case 'r':
+		if err := m.reload(); err != nil {
+			m.status = err.Error()
+		}
+	}
+
+	return false
+}
+
+func (m *model) startInput(md mode, text string) {
+	m.mode = md
+	m.input = []rune(text)
+}
+
+func (m *model) handleInput(k keyboard.Key) {
+	switch {
+	case k == keyboard.KeyEsc:
+		m.mode = modeBrowse
+		m.status = ""
+	case k == keyboard.KeyEnter:
+		m.submit()
+	case k == keyboard.KeyBackspace || k == keyboard.KeyBackspace2:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	case k >= keyboard.KeySpace && unicode.IsPrint(rune(k)):
+		m.input = append(m.input, rune(k))
+	}
+}
+
+func (m *model) submit() {
+	task := strings.TrimSpace(string(m.input))
+	md := m.mode
+	m.mode = modeBrowse
+	m.status = ""
+
+	if task == "" {
+		return
+	}
+
+	switch md {
+	case modeAdd:
+		m.apply(todo.OpAdd, func(l *todo.List) (int, error) {
+			l.Add(task)
+			return (*l)[len(*l)-1].ID, nil
+		})
+
+	case modeAddSubtask:
+		parent, ok := m.current()
+		if !ok {
+			return
+		}
+
+		m.apply(todo.OpAdd, func(l *todo.List) (int, error) {
+			if err := l.AddSubtask(parent, task); err != nil {
+				return 0, err
+			}
+
+			return (*l)[len(*l)-1].ID, nil
+		})
+
+	case modeEdit:
+		id, ok := m.current()
+		if !ok {
+			return
+		}
+
+		m.apply(todo.OpEdit, func(l *todo.List) (int, error) {
+			return id, l.Edit(id, task)
+		})
+	}
+}
+
+func (m *model) toggle() {
+	id, ok := m.current()
+	if !ok {
+		return
+	}
+
+	if m.view[m.selected].Done {
+		m.apply(todo.OpReopen, func(l *todo.List) (int, error) {
+			return id, l.Reopen(id)
+		})
+		return
+	}
+
+	m.apply(todo.OpComplete, func(l *todo.List) (int, error) {
+		return id, l.Complete(id)
+	})
+}
+
+func (m *model) delete() {
+	id, ok := m.current()
+	if !ok {
+		return
+	}
+
+	m.apply(todo.OpDelete, func(l *todo.List) (int, error) {
+		return id, l.Delete(id)
+	})
+}
+
+// apply saves the change made by fn and reloads the list, keeping the
+// changed item selected. Errors are shown in the status line.
+func (m *model) apply(op todo.Op, fn func(*todo.List) (int, error)) {
+	changed := 0
+
+	err := m.update(op, func(l *todo.List) (int, error) {
+		id, err := fn(l)
+		changed = id
+		return id, err
+	})
+	if err != nil {
+		m.status = err.Error()
+	}
+
+	if err := m.reload(); err != nil {
+		m.status = err.Error()
+		return
+	}
+
+	m.selectID(changed)
+}
+
+// lines returns the rendered list, one line per item of the view.
+func (m *model) lines() []string {
+	formatted := strings.TrimSuffix(m.view.Format(todo.SortNone), "\n")
+	if formatted == "" {
+		return nil
+	}
+
+	return strings.Split(formatted, "\n")
+}
+
+// window returns the range of lines to show in height rows, scrolling to
+// keep the selected item visible.
+func (m *model) window(height int) (int, int) {
+	height = max(height, 1)
+
+	switch {
+	case m.selected < m.offset:
+		m.offset = m.selected
+	case m.selected >= m.offset+height:
+		m.offset = m.selected - height + 1
+	}
+
+	m.offset = max(min(m.offset, len(m.view)-height), 0)
+
+	return m.offset, min(m.offset+height, len(m.view))
+}
+
+func (m *model) statusLine() string {
+	prompts := map[mode]string{
+		modeAdd:        "Add: ",
+		modeAddSubtask: "Add subtask: ",
+		modeEdit:       "Edit: ",
+	}
+
+	if prompt, ok := prompts[m.mode]; ok {
+		return prompt + string(m.input) + "█\n" + "enter save  esc cancel"
+	}
+
+	status := m.status
+	if m.pending {
+		status = strings.TrimSpace("[pending] " + status)
+	}
+
+	return status + "\n" + help
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mum4k/termdash/keyboard"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+)
+
+func newTestModel(t *testing.T) (*model, todo.Repository) {
+	t.Helper()
+
+	repo := todo.NewFileStore(filepath.Join(t.TempDir(), "todo.json"))
+
+	update := func(op todo.Op, fn func(*todo.List) (int, error)) error {
+		return todo.Update(repo, &todo.List{}, func(l *todo.List) error {
+			_, err := fn(l)
+			return err
+		})
+	}
+
+	m, err := newModel(repo, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m, repo
+}
+
+func typeKeys(m *model, keys ...any) {
+	for _, k := range keys {
+		switch k := k.(type) {
+		case string:
+			for _, r := range k {
+				m.handleKey(keyboard.Key(r))
+			}
+		case keyboard.Key:
+			m.handleKey(k)
+		case rune:
+			m.handleKey(keyboard.Key(k))
+		}
+	}
+}
+
+func TestModel(t *testing.T) {
+	m, repo := newTestModel(t)
+
+	testCases := []struct {
+		name     string
+		keys     []any
+		expected string
+	}{
+		{"Add", []any{"aTask 1", keyboard.KeyEnter, "aTask 2", keyboard.KeyEnter},
+			"[ ] 1: Task 1\n[ ] 2: Task 2\n"},
+		{"AddCancel", []any{"aIgnored", keyboard.KeyEsc},
+			"[ ] 1: Task 1\n[ ] 2: Task 2\n"},
+		{"AddSubtask", []any{keyboard.KeyHome, "sStep", keyboard.KeyEnter},
+			"[ ] 1: Task 1\n  [ ] 3: Step\n[ ] 2: Task 2\n"},
+		{"Edit", []any{keyboard.KeyEnd, "e", keyboard.KeyBackspace2, "two", keyboard.KeyEnter},
+			"[ ] 1: Task 1\n  [ ] 3: Step\n[ ] 2: Task two\n"},
+		{"CompletePendingSubtasks", []any{keyboard.KeyHome, keyboard.KeySpace},
+			"[ ] 1: Task 1\n  [ ] 3: Step\n[ ] 2: Task two\n"},
+		{"Toggle", []any{keyboard.KeyArrowDown, keyboard.KeySpace, keyboard.KeyArrowUp, keyboard.KeySpace},
+			"[X] 1: Task 1\n  [X] 3: Step\n[ ] 2: Task two\n"},
+		{"Reopen", []any{keyboard.KeyArrowDown, keyboard.KeySpace},
+			"[X] 1: Task 1\n  [ ] 3: Step\n[ ] 2: Task two\n"},
+		{"Delete", []any{keyboard.KeyHome, "d"},
+			"[ ] 2: Task two\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			typeKeys(m, tc.keys...)
+
+			l := todo.List{}
+			if err := repo.Get(&l); err != nil {
+				t.Fatal(err)
+			}
+
+			if res := l.String(); res != tc.expected {
+				t.Errorf("Expected %q, got %q instead.", tc.expected, res)
+			}
+		})
+	}
+
+	if m.status != "" {
+		t.Errorf("Expected no status, got %q instead.", m.status)
+	}
+}
+
+func TestModelStatus(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	typeKeys(m, "aParent", keyboard.KeyEnter, "sChild", keyboard.KeyEnter, keyboard.KeyHome, keyboard.KeySpace)
+
+	if m.status == "" || m.view[m.selected].Done {
+		t.Errorf("Expected completing a parent with pending subtasks to fail, got status %q", m.status)
+	}
+}
+
+func TestModelFilterPending(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	typeKeys(m, "aDone", keyboard.KeyEnter, "aPending", keyboard.KeyEnter, keyboard.KeyHome, keyboard.KeySpace, "p")
+
+	expected := []string{"[ ] 2: Pending"}
+	if lines := m.lines(); len(lines) != 1 || lines[0] != expected[0] {
+		t.Errorf("Expected %q, got %q instead.", expected, lines)
+	}
+}
+
+func TestModelWindow(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	for range 10 {
+		typeKeys(m, "aTask", keyboard.KeyEnter)
+	}
+
+	typeKeys(m, keyboard.KeyHome)
+
+	if start, end := m.window(3); start != 0 || end != 3 {
+		t.Errorf("Expected window [0, 3), got [%d, %d) instead.", start, end)
+	}
+
+	typeKeys(m, keyboard.KeyEnd)
+
+	if start, end := m.window(3); start != 7 || end != 10 {
+		t.Errorf("Expected window [7, 10), got [%d, %d) instead.", start, end)
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+type widgets struct {
+	txtList   *text.Text
+	txtStatus *text.Text
+}
+
+func newWidgets() (*widgets, error) {
+	w := &widgets{}
+
+	var err error
+
+	w.txtList, err = text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	w.txtStatus, err = text.New(text.WrapAtWords(), text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// update renders the model, showing as many items as fit in height rows.
+func (w *widgets) update(m *model, height int) error {
+	w.txtList.Reset()
+
+	lines := m.lines()
+	if len(lines) == 0 {
+		if err := w.txtList.Write("Nothing to do. Press A to add a task.",
+			text.WriteCellOpts(cell.FgColor(cell.ColorGray))); err != nil {
+			return err
+		}
+	}
+
+	start, end := m.window(height)
+	for i := start; i < end; i++ {
+		opts := []text.WriteOption{}
+
+		switch {
+		case i == m.selected:
+			opts = append(opts, text.WriteCellOpts(cell.Inverse()))
+		case m.view[i].Done:
+			opts = append(opts, text.WriteCellOpts(cell.FgColor(cell.ColorGray)))
+		}
+
+		if err := w.txtList.Write(lines[i]+"\n", opts...); err != nil {
+			return err
+		}
+	}
+
+	w.txtStatus.Reset()
+
+	return w.txtStatus.Write(m.statusLine())
+}
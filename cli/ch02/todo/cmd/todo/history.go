@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+)
+
+// historyFile keeps the history beside local lists. Remote lists get one
+// in the working directory. Named lists other than the default get their
+// own history.
+func historyFile(location, list string) string {
+	suffix := ".history"
+	if list != "" && list != todo.DefaultList {
+		suffix = "." + list + suffix
+	}
+
+	scheme, path, found := strings.Cut(location, "://")
+	if !found {
+		return location + suffix
+	}
+
+	switch scheme {
+	case "file", "sqlite", "sqlite3":
+		return path + suffix
+	}
+
+	return ".todo" + suffix
+}
+
+// update applies fn through the repository and records the change it made
+// in the history. fn returns the ID of the item it changed.
+func update(repo todo.Repository, hist *todo.History, op todo.Op, fn func(*todo.List) (int, error)) error {
+	return hist.Update(repo, &todo.List{}, op, fn)
+}
+
+func revert(repo todo.Repository, hist *todo.History, redo bool) error {
+	var change todo.Change
+
+	l := &todo.List{}
+	err := todo.Update(repo, l, func(l *todo.List) error {
+		var err error
+
+		if redo {
+			change, err = hist.Redo(l)
+		} else {
+			change, err = hist.Undo(l)
+		}
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return hist.Record(change)
+}
+
+// importFiles merges the tasks read from files, or STDIN when none are
+// given, recording an add in the history for every imported task, so undo
+// removes them one at a time.
+func importFiles(repo todo.Repository, hist *todo.History, format string, files ...string) (int, error) {
+	sources := [][]byte{}
+
+	if len(files) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return 0, err
+		}
+		sources = append(sources, data)
+	}
+
+	for _, fname := range files {
+		data, err := os.ReadFile(fname)
+		if err != nil {
+			return 0, err
+		}
+		sources = append(sources, data)
+	}
+
+	added := 0
+
+	l := &todo.List{}
+	err := todo.Update(repo, l, func(l *todo.List) error {
+		added = 0
+
+		for _, data := range sources {
+			n, err := l.Import(bytes.NewReader(data), format)
+			if err != nil {
+				return err
+			}
+			added += n
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Imported tasks are appended, so each one is added to the tasks
+	// before it.
+	for i := len(*l) - added; i < len(*l); i++ {
+		if err := hist.Record(todo.NewChange(todo.OpAdd, (*l)[i].ID, (*l)[:i], (*l)[:i+1])); err != nil {
+			return added, err
+		}
+	}
+
+	return added, nil
+}
@@ -8,9 +8,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	todo "github.com/ZeroBl21/cli/ch02"
+	"github.com/ZeroBl21/cli/ch02/app"
 )
 
 var todoFileName = ".todo.json"
@@ -18,7 +21,7 @@ var todoFileName = ".todo.json"
 func init() {
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(),
-			"Usage:\n  %s [flag] <input>\n\nExample:\n  %[1]s -add Chore\n\nFlags:\n",
+			"Usage:\n  %s [flag] <input>\n  %[1]s serve [-addr host:port] [-store location]\n\nExample:\n  %[1]s -add Chore\n\nFlags:\n",
 			filepath.Base(os.Args[0]))
 
 		flag.PrintDefaults()
@@ -26,65 +29,287 @@ func init() {
 }
 
 func main() {
+	if os.Getenv("TODO_FILENAME") != "" {
+		todoFileName = os.Getenv("TODO_FILENAME")
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		if err := serve(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	add := flag.Bool("add", false, "Add task to the to do list")
 	list := flag.Bool("list", false, "List all tasks")
 	pending := flag.Bool("pending", false, "List all pending tasks")
-	complete := flag.Int("complete", 0, "Item to be completed")
-	delete := flag.Int("del", 0, "Item to be deleted")
+	complete := flag.Int("complete", 0, "ID of the item to be completed")
+	delete := flag.Int("del", 0, "ID of the item to be deleted")
+	edit := flag.Int("edit", 0, "ID of the item to replace the task text of")
+	undo := flag.Bool("undo", false, "Revert the last change")
+	redo := flag.Bool("redo", false, "Replay the last reverted change")
+	history := flag.Bool("history", false, "Show the history of changes")
+	tui := flag.Bool("tui", false, "Open the interactive terminal UI")
+	export := flag.String("export", "", "Write the list to STDOUT as todo.txt, csv or markdown")
+	imports := flag.String("import", "", "Merge todo.txt, csv or markdown tasks from files or STDIN")
+
+	priority := flag.String("priority", "", "Task priority (low, medium, high) to set or filter by")
+	due := flag.String("due", "", "Due date (YYYY-MM-DD, today, tomorrow, +Nd) to set or list tasks due by")
+	tags := flag.String("tag", "", "Comma separated tags to set or filter by")
+	repeat := flag.String("repeat", "", "Recurrence: daily, weekly[:mon,fri], monthly[:31] or cron:<spec>, prefixed by after: to count from completion")
+	overdue := flag.Bool("overdue", false, "List only overdue tasks")
+	sortBy := flag.String("sort", "", "Sort listed tasks by created, priority or due")
+	storage := flag.String("store", "", "Storage location: file path or file://, sqlite:// or http:// URL")
+	listName := flag.String("list-name", todo.DefaultList, "Name of the list to work on")
+	parent := flag.Int("parent", 0, "ID of the item to add the task under as a subtask")
+	cascade := flag.Bool("cascade", false, "Complete the pending subtasks of the item too")
 
 	flag.Parse()
 
-	if os.Getenv("TODO_FILENAME") != "" {
-		todoFileName = os.Getenv("TODO_FILENAME")
+	if *storage != "" {
+		todoFileName = *storage
+	}
+
+	repo, err := getRepo(todoFileName, *listName)
+	if err != nil {
+		log.Fatal(err)
 	}
 
+	hist := todo.NewHistory(historyFile(todoFileName, *listName))
 	l := &todo.List{}
 
-	if err := l.Get(todoFileName); err != nil {
+	if err := repo.Get(l); err != nil {
 		log.Fatal(err)
 	}
 
 	switch {
+	case *tui:
+		a, err := app.New(repo, func(op todo.Op, fn func(*todo.List) (int, error)) error {
+			return update(repo, hist, op, fn)
+		}, *listName)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := a.Run(); err != nil {
+			log.Fatal(err)
+		}
+
 	case *add:
 		t, err := getTask(os.Stdin, flag.Args()...)
 		if err != nil {
 			log.Fatal(err)
 		}
-		l.Add(t)
+		opts, err := itemOptions(*priority, *due, *tags, *repeat)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = update(repo, hist, todo.OpAdd, func(l *todo.List) (int, error) {
+			if *parent > 0 {
+				if err := l.AddSubtask(*parent, t, opts...); err != nil {
+					return 0, err
+				}
+			} else {
+				l.Add(t, opts...)
+			}
 
-		if err := l.Save(todoFileName); err != nil {
+			return (*l)[len(*l)-1].ID, nil
+		})
+		if err != nil {
 			log.Fatal(err)
 		}
 
-	case *list:
-		fmt.Print(l)
+	case *list, *pending, *overdue:
+		filters, err := listFilters(*priority, *due, *tags, *overdue, *pending)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	case *pending:
-		fmt.Print(l.Pending())
+		by, err := todo.ParseSortKey(*sortBy)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Print(l.Format(by, filters...))
 
 	case *complete > 0:
-		if err := l.Complete(*complete); err != nil {
+		err := update(repo, hist, todo.OpComplete, func(l *todo.List) (int, error) {
+			if *cascade {
+				return *complete, l.CompleteCascade(*complete)
+			}
+
+			return *complete, l.Complete(*complete)
+		})
+		if err != nil {
 			log.Fatal(err)
 		}
 
-		if err := l.Save(todoFileName); err != nil {
+	case *delete > 0:
+		err := update(repo, hist, todo.OpDelete, func(l *todo.List) (int, error) {
+			return *delete, l.Delete(*delete)
+		})
+		if err != nil {
 			log.Fatal(err)
 		}
 
-	case *delete > 0:
-		if err := l.Delete(*delete); err != nil {
+	case *edit > 0:
+		t, err := getTask(os.Stdin, flag.Args()...)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = update(repo, hist, todo.OpEdit, func(l *todo.List) (int, error) {
+			return *edit, l.Edit(*edit, t)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case *undo, *redo:
+		if err := revert(repo, hist, *redo); err != nil {
+			log.Fatal(err)
+		}
+
+	case *export != "":
+		format, err := todo.ParseFormat(*export)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := l.Export(os.Stdout, format); err != nil {
+			log.Fatal(err)
+		}
+
+	case *imports != "":
+		format, err := todo.ParseFormat(*imports)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		n, err := importFiles(repo, hist, format, flag.Args()...)
+		if err != nil {
 			log.Fatal(err)
 		}
 
-		if err := l.Save(todoFileName); err != nil {
+		fmt.Printf("Imported %d tasks\n", n)
+
+	case *history:
+		entries, err := hist.Entries()
+		if err != nil {
 			log.Fatal(err)
 		}
 
+		for _, c := range entries {
+			fmt.Println(c)
+		}
+
 	default:
 		flag.Usage()
 	}
 }
 
+func itemOptions(priority, due, tags, repeat string) ([]todo.ItemOption, error) {
+	opts := []todo.ItemOption{}
+
+	if priority != "" {
+		p, err := todo.ParsePriority(priority)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, todo.WithPriority(p))
+	}
+
+	if due != "" {
+		d, err := parseDate(due, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, todo.WithDue(d))
+	}
+
+	if tags != "" {
+		opts = append(opts, todo.WithTags(strings.Split(tags, ",")...))
+	}
+
+	if repeat != "" {
+		r, err := todo.ParseRecurrence(repeat)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, todo.WithRecurrence(r))
+	}
+
+	return opts, nil
+}
+
+func listFilters(priority, due, tags string, overdue, pending bool) ([]todo.Filter, error) {
+	filters := []todo.Filter{}
+
+	if pending {
+		filters = append(filters, todo.IsPending)
+	}
+
+	if priority != "" {
+		p, err := todo.ParsePriority(priority)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, todo.HasPriority(p))
+	}
+
+	if due != "" {
+		d, err := parseDate(due, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, todo.DueBefore(d))
+	}
+
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filters = append(filters, todo.HasTag(t))
+		}
+	}
+
+	if overdue {
+		filters = append(filters, todo.Overdue(time.Now()))
+	}
+
+	return filters, nil
+}
+
+// parseDate accepts an ISO date, "today", "tomorrow" or a "+Nd" offset
+// in days from now.
+func parseDate(s string, now time.Time) (time.Time, error) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+
+	switch s {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if strings.HasPrefix(s, "+") && strings.HasSuffix(s, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s[1:], "d"))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid date %q: %w", s, err)
+		}
+
+		return today.AddDate(0, 0, n), nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid date %q: %w", s, err)
+	}
+
+	return t, nil
+}
+
 func getTask(r io.Reader, args ...string) (string, error) {
 	if len(args) > 0 {
 		return strings.Join(args, " "), nil
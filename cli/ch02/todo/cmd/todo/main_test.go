@@ -1,6 +1,7 @@
 package main_test
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"log"
@@ -8,7 +9,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -39,6 +42,9 @@ func TestMain(m *testing.M) {
 	fmt.Println("Cleaning up...")
 	os.Remove(binName)
 	os.Remove(fileName)
+	os.Remove(fileName + ".lock")
+	os.Remove(fileName + ".history")
+	os.Remove(fileName + ".history.lock")
 
 	os.Exit(result)
 }
@@ -122,4 +128,267 @@ func TestTodoCLI(t *testing.T) {
 			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
 		}
 	})
+	task4 := "test task number 4"
+	t.Run("Add And Filter Task With Priority And Tag", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-add", "-priority", "high", "-tag", "work", task4)
+
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-list", "-tag", "work")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := fmt.Sprintf("[ ] 4: %s (high) #work\n", task4)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("Add Tasks Concurrently", func(t *testing.T) {
+		const n = 5
+		cmds := make([]*exec.Cmd, n)
+
+		for i := range cmds {
+			cmds[i] = exec.Command(cmdPath, "-add", fmt.Sprintf("concurrent task %d", i))
+			if err := cmds[i].Start(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, cmd := range cmds {
+			if err := cmd.Wait(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-list")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if lines := strings.Count(string(out), "\n"); lines != 3+n {
+			t.Errorf("Expected %d tasks, got %d instead\n", 3+n, lines)
+		}
+	})
+	t.Run("Use SQLite Storage", func(t *testing.T) {
+		store := "sqlite://" + filepath.Join(t.TempDir(), "todo.db")
+
+		cmd := exec.Command(cmdPath, "-store", store, "-add", task)
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-store", store, "-list")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := fmt.Sprintf("[ ] 1: %s\n", task)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("Edit Undo And Redo", func(t *testing.T) {
+		steps := []struct {
+			args     []string
+			expected string
+		}{
+			{[]string{"-edit", "1", "edited task"}, "[ ] 1: edited task\n"},
+			{[]string{"-undo"}, fmt.Sprintf("[ ] 1: %s\n", task)},
+			{[]string{"-redo"}, "[ ] 1: edited task\n"},
+			{[]string{"-del", "1"}, ""},
+			{[]string{"-undo"}, "[ ] 1: edited task\n"},
+		}
+
+		for _, s := range steps {
+			cmd := exec.Command(cmdPath, s.args...)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%v: %s: %s", s.args, err, out)
+			}
+
+			cmd = exec.Command(cmdPath, "-list")
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := ""
+			for _, line := range strings.SplitAfter(string(out), "\n") {
+				if strings.HasPrefix(line, "[ ] 1: ") {
+					res = line
+				}
+			}
+
+			if res != s.expected {
+				t.Errorf("%v: expected %q, got %q instead\n", s.args, s.expected, res)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-history")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(out), "edit") || !strings.Contains(string(out), "undo") {
+			t.Errorf("Expected history with edit and undo entries, got %q instead\n", out)
+		}
+	})
+	t.Run("Complete Recurring Task", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-add", "-repeat", "daily", "-due", "today", "-tag", "standup", "daily standup")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-list", "-tag", "standup")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id, _, _ := strings.Cut(strings.TrimPrefix(string(out), "[ ] "), ":")
+
+		cmd = exec.Command(cmdPath, "-complete", id)
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-pending", "-tag", "standup")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+		if !strings.Contains(string(out), "due:"+tomorrow+" repeat:daily #standup") {
+			t.Errorf("Expected next occurrence due %s, got %q instead\n", tomorrow, out)
+		}
+	})
+	t.Run("Export And Import", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-export", "md")
+		exported, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		store := filepath.Join(t.TempDir(), "todo.json")
+
+		for _, expected := range []string{fmt.Sprintf("Imported %d tasks\n", bytes.Count(exported, []byte("\n"))), "Imported 0 tasks\n"} {
+			cmd = exec.Command(cmdPath, "-store", store, "-import", "markdown")
+			cmd.Stdin = bytes.NewReader(exported)
+
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+
+			if expected != string(out) {
+				t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+			}
+		}
+
+		list := exec.Command(cmdPath, "-list")
+		listImported := exec.Command(cmdPath, "-store", store, "-list")
+
+		exp, err := list.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := listImported.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(bytes.Split(exp, []byte("\n"))) != len(bytes.Split(res, []byte("\n"))) {
+			t.Errorf("Expected %q, got %q instead\n", exp, res)
+		}
+	})
+
+	t.Run("Undo Import", func(t *testing.T) {
+		store := filepath.Join(t.TempDir(), "todo.json")
+
+		cmd := exec.Command(cmdPath, "-store", store, "-import", "markdown")
+		cmd.Stdin = strings.NewReader("- [ ] one\n- [ ] two\n- [ ] three\n")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		for _, expected := range []string{
+			"[ ] 1: one\n[ ] 2: two\n",
+			"[ ] 1: one\n",
+			"",
+		} {
+			cmd = exec.Command(cmdPath, "-store", store, "-undo")
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+
+			cmd = exec.Command(cmdPath, "-store", store, "-list")
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if expected != string(out) {
+				t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+			}
+		}
+	})
+	t.Run("Named List With Subtasks", func(t *testing.T) {
+		store := filepath.Join(t.TempDir(), "todo.json")
+
+		steps := [][]string{
+			{"-store", store, "-add", "Default task"},
+			{"-store", store, "-list-name", "work", "-add", "Release"},
+			{"-store", store, "-list-name", "work", "-parent", "1", "-add", "Tag version"},
+		}
+		for _, args := range steps {
+			if out, err := exec.Command(cmdPath, args...).CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+		}
+
+		if err := exec.Command(cmdPath, "-store", store, "-list-name", "work", "-complete", "1").Run(); err == nil {
+			t.Error("Expected completing an item with pending subtasks to fail")
+		}
+
+		cmd := exec.Command(cmdPath, "-store", store, "-list-name", "work", "-cascade", "-complete", "1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		for list, expected := range map[string]string{
+			"default": "[ ] 1: Default task\n",
+			"work":    "[X] 1: Release\n  [X] 2: Tag version\n",
+		} {
+			out, err := exec.Command(cmdPath, "-store", store, "-list-name", list, "-list").CombinedOutput()
+			if err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+
+			if expected != string(out) {
+				t.Errorf("Expected list %q to be %q, got %q instead\n", list, expected, string(out))
+			}
+		}
+
+		cmd = exec.Command(cmdPath, "-store", store, "-list-name", "work", "-undo")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		out, err := exec.Command(cmdPath, "-store", store, "-list-name", "work", "-pending").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := "[ ] 1: Release\n  [ ] 2: Tag version\n"; expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
 }
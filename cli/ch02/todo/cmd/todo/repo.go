@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+	"github.com/ZeroBl21/cli/ch02/repository"
+)
+
+// getRepo selects the storage backend from a location such as
+// "file://.todo.json", "sqlite://todo.db" or "http://host:8080/todo".
+// Locations without a scheme are JSON files. list names the list to open
+// within the location.
+func getRepo(location, list string) (todo.Repository, error) {
+	scheme, path, found := strings.Cut(location, "://")
+	if !found {
+		return todo.NewNamedFileStore(location, list), nil
+	}
+
+	switch scheme {
+	case "file":
+		return todo.NewNamedFileStore(path, list), nil
+	case "sqlite", "sqlite3":
+		return repository.NewSQLiteRepo(path, list)
+	case "http", "https":
+		return repository.NewHTTPRepo(listURL(location, list)), nil
+	}
+
+	return nil, fmt.Errorf("Unsupported storage %q", scheme)
+}
+
+func listURL(location, list string) string {
+	if list == "" || list == todo.DefaultList {
+		return location
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+
+	q := u.Query()
+	q.Set("list", list)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
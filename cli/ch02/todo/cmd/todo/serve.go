@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+	"github.com/ZeroBl21/cli/ch02/server"
+)
+
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "Listen address")
+	storage := fs.String("store", todoFileName, "Storage location: file path or file:// or sqlite:// URL")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	open := func(list string) (todo.Repository, *todo.History, error) {
+		repo, err := getRepo(*storage, list)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return repo, todo.NewHistory(historyFile(*storage, list)), nil
+	}
+
+	if _, _, err := open(todo.DefaultList); err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.NewHandler(open),
+		IdleTimeout:       time.Minute,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+
+		<-c
+		if err := srv.Shutdown(context.Background()); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+		close(done)
+	}()
+
+	log.Printf("Serving %q over %s\n", *storage, srv.Addr)
+
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	<-done
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package todo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultList names the list used when none is given.
+const DefaultList = "default"
+
+// FileStore keeps a List as JSON on disk. Every access holds an advisory
+// lock on a sidecar ".lock" file, writes go through a temp file and a
+// rename, and Save refuses to overwrite a file that changed after Get.
+//
+// A file holds several named lists as {"lists": {"name": [...]}}. While it
+// only holds the default list, it is written as a plain array, as it was
+// before named lists existed.
+type FileStore struct {
+	filename string
+	list     string
+	loaded   bool
+	version  [sha256.Size]byte
+}
+
+type document struct {
+	Lists map[string]List `json:"lists"`
+}
+
+func NewFileStore(filename string) *FileStore {
+	return NewNamedFileStore(filename, DefaultList)
+}
+
+func NewNamedFileStore(filename, list string) *FileStore {
+	if list == "" {
+		list = DefaultList
+	}
+
+	return &FileStore{
+		filename: filename,
+		list:     list,
+	}
+}
+
+func decodeLists(data []byte) (map[string]List, error) {
+	lists := map[string]List{}
+
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return lists, nil
+
+	case data[0] == '[':
+		l := List{}
+		if err := json.Unmarshal(data, &l); err != nil {
+			return nil, err
+		}
+		lists[DefaultList] = l
+
+		return lists, nil
+	}
+
+	doc := document{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	for name, l := range doc.Lists {
+		lists[name] = l
+	}
+
+	return lists, nil
+}
+
+func encodeLists(lists map[string]List) ([]byte, error) {
+	for name, l := range lists {
+		if name != DefaultList && len(l) == 0 {
+			delete(lists, name)
+		}
+	}
+
+	if _, ok := lists[DefaultList]; ok && len(lists) == 1 {
+		return json.Marshal(lists[DefaultList])
+	}
+
+	return json.Marshal(document{Lists: lists})
+}
+
+// Names returns the names of the lists kept in the file.
+func (s *FileStore) Names() ([]string, error) {
+	unlock, err := lock(s.filename, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := readFile(s.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := decodeLists(data)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (s *FileStore) Get(l *List) error {
+	unlock, err := lock(s.filename, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := readFile(s.filename)
+	if err != nil {
+		return err
+	}
+
+	lists, err := decodeLists(data)
+	if err != nil {
+		return err
+	}
+
+	*l = List{}
+	if stored, ok := lists[s.list]; ok {
+		*l = stored
+	}
+
+	s.version = sha256.Sum256(data)
+	s.loaded = true
+
+	return nil
+}
+
+func (s *FileStore) Save(l *List) error {
+	unlock, err := lock(s.filename, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := readFile(s.filename)
+	if err != nil {
+		return err
+	}
+
+	if s.loaded && sha256.Sum256(current) != s.version {
+		return fmt.Errorf("%w: %s", ErrConflict, s.filename)
+	}
+
+	lists, err := decodeLists(current)
+	if err != nil {
+		return err
+	}
+	lists[s.list] = *l
+
+	js, err := encodeLists(lists)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(s.filename, js, 0644); err != nil {
+		return err
+	}
+
+	s.version = sha256.Sum256(js)
+	s.loaded = true
+
+	return nil
+}
+
+func lock(filename string, exclusive bool) (func() error, error) {
+	f, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Cannot lock %s: %w", filename, err)
+	}
+
+	return func() error {
+		if err := unlockFile(f); err != nil {
+			f.Close()
+			return err
+		}
+
+		return f.Close()
+	}, nil
+}
+
+func readFile(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	temp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(temp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), filename)
+}
@@ -0,0 +1,575 @@
+package todo
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatTodoTxt  = "todo.txt"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+var ErrInvalidFormat = errors.New("Invalid format")
+
+var csvHeader = []string{
+	"id", "task", "done", "priority", "due", "tags", "repeat", "created_at", "completed_at", "parent",
+}
+
+func ParseFormat(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "todo.txt", "todotxt", "txt":
+		return FormatTodoTxt, nil
+	case "csv":
+		return FormatCSV, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrInvalidFormat, s)
+}
+
+func (l *List) Export(w io.Writer, format string) error {
+	switch format {
+	case FormatTodoTxt:
+		return l.exportTodoTxt(w)
+	case FormatCSV:
+		return l.exportCSV(w)
+	case FormatMarkdown:
+		return l.exportMarkdown(w)
+	}
+
+	return fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+}
+
+// Import merges the items read from r into the list, skipping the ones
+// already present with the same task and creation time, and returns the
+// number of items added. todo.txt only keeps creation dates, so its items
+// match on the day. Subtasks are attached to their imported or matching
+// parent.
+func (l *List) Import(r io.Reader, format string) (int, error) {
+	var (
+		items []item
+		err   error
+		byDay bool
+	)
+
+	switch format {
+	case FormatTodoTxt:
+		items, err = importTodoTxt(r)
+		byDay = true
+	case FormatCSV:
+		items, err = importCSV(r)
+	case FormatMarkdown:
+		items, err = importMarkdown(r)
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	now := time.Now()
+	ids := map[int]int{}
+
+	for _, t := range items {
+		src := t.ID
+
+		if id, ok := l.match(t, byDay); ok {
+			if src != 0 {
+				ids[src] = id
+			}
+			continue
+		}
+
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = now
+		}
+
+		t.ID = l.nextID()
+		*l = append(*l, t)
+		added++
+
+		if src != 0 {
+			ids[src] = t.ID
+		}
+	}
+
+	ls := *l
+	for i := len(ls) - added; i < len(ls); i++ {
+		if ls[i].Parent == 0 {
+			continue
+		}
+
+		ls[i].Parent = ids[ls[i].Parent]
+
+		if idx, err := l.Find(ls[i].Parent); err != nil || ls[idx].Parent != 0 {
+			ls[i].Parent = 0
+		}
+	}
+
+	return added, nil
+}
+
+// match returns the ID of the item in the list t duplicates.
+func (l *List) match(t item, byDay bool) (int, bool) {
+	for _, e := range *l {
+		if e.Task != t.Task {
+			continue
+		}
+
+		switch {
+		case t.CreatedAt.IsZero():
+			return e.ID, true
+		case byDay:
+			y1, m1, d1 := e.CreatedAt.Date()
+			y2, m2, d2 := t.CreatedAt.Date()
+			if y1 == y2 && m1 == m2 && d1 == d2 {
+				return e.ID, true
+			}
+		case e.CreatedAt.Equal(t.CreatedAt):
+			return e.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// encodeRule keeps recurrence rules, which may contain spaces, inside a
+// single key:value token.
+func encodeRule(rule string) string {
+	return strings.ReplaceAll(rule, " ", "_")
+}
+
+func decodeRule(rule string) (string, error) {
+	r, err := ParseRecurrence(strings.ReplaceAll(rule, "_", " "))
+	if err != nil {
+		return "", err
+	}
+
+	return r.String(), nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+var todoTxtPriorities = map[Priority]string{
+	PriorityHigh:   "A",
+	PriorityMedium: "B",
+	PriorityLow:    "C",
+}
+
+func parseTodoTxtPriority(s string) Priority {
+	switch s {
+	case "A":
+		return PriorityHigh
+	case "B":
+		return PriorityMedium
+	}
+
+	return PriorityLow
+}
+
+func (l *List) exportTodoTxt(w io.Writer) error {
+	for _, t := range *l {
+		fields := []string{}
+
+		if t.Done {
+			fields = append(fields, "x")
+			if !t.CompletedAt.IsZero() {
+				fields = append(fields, t.CompletedAt.Format(time.DateOnly))
+			}
+		} else if p, ok := todoTxtPriorities[t.Priority]; ok {
+			fields = append(fields, "("+p+")")
+		}
+
+		fields = append(fields, t.CreatedAt.Format(time.DateOnly), t.Task)
+
+		for _, tag := range t.Tags {
+			if !strings.HasPrefix(tag, "@") {
+				tag = "+" + tag
+			}
+			fields = append(fields, tag)
+		}
+
+		if !t.Due.IsZero() {
+			fields = append(fields, "due:"+t.Due.Format(time.DateOnly))
+		}
+
+		if t.Repeat != "" {
+			fields = append(fields, "rec:"+encodeRule(t.Repeat))
+		}
+
+		if p, ok := todoTxtPriorities[t.Priority]; ok && t.Done {
+			fields = append(fields, "pri:"+p)
+		}
+
+		if len(l.subtasks(t.ID)) > 0 {
+			fields = append(fields, "id:"+strconv.Itoa(t.ID))
+		}
+
+		if t.Parent != 0 {
+			fields = append(fields, "parent:"+strconv.Itoa(t.Parent))
+		}
+
+		if _, err := fmt.Fprintln(w, strings.Join(fields, " ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var todoTxtPriorityRe = regexp.MustCompile(`^\(([A-Z])\)$`)
+
+func isDate(s string) bool {
+	_, err := time.Parse(time.DateOnly, s)
+	return err == nil
+}
+
+func importTodoTxt(r io.Reader) ([]item, error) {
+	items := []item{}
+	s := bufio.NewScanner(r)
+
+	for n := 1; s.Scan(); n++ {
+		words := strings.Fields(s.Text())
+		if len(words) == 0 {
+			continue
+		}
+
+		t := item{}
+
+		if words[0] == "x" {
+			t.Done = true
+			words = words[1:]
+
+			if len(words) > 0 && isDate(words[0]) {
+				t.CompletedAt, _ = parseTime(words[0])
+				words = words[1:]
+			}
+		}
+
+		if len(words) > 0 {
+			if m := todoTxtPriorityRe.FindStringSubmatch(words[0]); m != nil {
+				t.Priority = parseTodoTxtPriority(m[1])
+				words = words[1:]
+			}
+		}
+
+		if len(words) > 0 && isDate(words[0]) {
+			t.CreatedAt, _ = parseTime(words[0])
+			words = words[1:]
+		}
+
+		text := []string{}
+		for _, word := range words {
+			key, value, _ := strings.Cut(word, ":")
+
+			var err error
+			switch {
+			case len(word) > 1 && word[0] == '+':
+				t.Tags = append(t.Tags, word[1:])
+			case len(word) > 1 && word[0] == '@':
+				t.Tags = append(t.Tags, word)
+			case key == "due" && isDate(value):
+				t.Due, err = parseTime(value)
+			case key == "rec" && value != "":
+				t.Repeat, err = decodeRule(value)
+			case key == "pri" && len(value) == 1:
+				t.Priority = parseTodoTxtPriority(value)
+			case key == "id" && value != "":
+				t.ID, err = strconv.Atoi(value)
+			case key == "parent" && value != "":
+				t.Parent, err = strconv.Atoi(value)
+			default:
+				text = append(text, word)
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("Line %d: %w", n, err)
+			}
+		}
+
+		t.Task = strings.Join(text, " ")
+		if t.Task == "" {
+			return nil, fmt.Errorf("Line %d: %w", n, ErrBlankTask)
+		}
+
+		items = append(items, t)
+	}
+
+	return items, s.Err()
+}
+
+func (l *List) exportCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, t := range *l {
+		record := []string{
+			strconv.Itoa(t.ID),
+			t.Task,
+			strconv.FormatBool(t.Done),
+			t.Priority.String(),
+			formatTime(t.Due),
+			strings.Join(t.Tags, ","),
+			t.Repeat,
+			formatTime(t.CreatedAt),
+			formatTime(t.CompletedAt),
+			strconv.Itoa(t.Parent),
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func importCSV(r io.Reader) ([]item, error) {
+	cr := csv.NewReader(r)
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["task"]; !ok {
+		return nil, fmt.Errorf("%w: CSV header has no task column", ErrInvalidFormat)
+	}
+
+	items := []item{}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+
+			return ""
+		}
+
+		t, err := csvItem(field)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %w", line, err)
+		}
+
+		items = append(items, t)
+	}
+}
+
+func csvItem(field func(string) string) (item, error) {
+	t := item{Task: field("task")}
+
+	if t.Task == "" {
+		return t, ErrBlankTask
+	}
+
+	var err error
+
+	for name, v := range map[string]*int{"id": &t.ID, "parent": &t.Parent} {
+		if n := field(name); n != "" {
+			if *v, err = strconv.Atoi(n); err != nil {
+				return t, err
+			}
+		}
+	}
+
+	if done := field("done"); done != "" {
+		if t.Done, err = strconv.ParseBool(done); err != nil {
+			return t, err
+		}
+	}
+
+	if t.Priority, err = ParsePriority(field("priority")); err != nil {
+		return t, err
+	}
+
+	if t.Due, err = parseTime(field("due")); err != nil {
+		return t, err
+	}
+
+	if t.CreatedAt, err = parseTime(field("created_at")); err != nil {
+		return t, err
+	}
+
+	if t.CompletedAt, err = parseTime(field("completed_at")); err != nil {
+		return t, err
+	}
+
+	if rule := field("repeat"); rule != "" {
+		if t.Repeat, err = decodeRule(rule); err != nil {
+			return t, err
+		}
+	}
+
+	WithTags(strings.Split(field("tags"), ",")...)(&t)
+
+	return t, nil
+}
+
+func (l *List) exportMarkdown(w io.Writer) error {
+	for _, t := range l.Tree() {
+		box := "[ ]"
+		if t.Done {
+			box = "[x]"
+		}
+
+		meta := []string{"created:" + formatTime(t.CreatedAt)}
+
+		if t.Priority != PriorityNone {
+			meta = append(meta, "priority:"+t.Priority.String())
+		}
+
+		if !t.Due.IsZero() {
+			meta = append(meta, "due:"+formatTime(t.Due))
+		}
+
+		if len(t.Tags) > 0 {
+			meta = append(meta, "tags:"+strings.Join(t.Tags, ","))
+		}
+
+		if t.Repeat != "" {
+			meta = append(meta, "repeat:"+encodeRule(t.Repeat))
+		}
+
+		if !t.CompletedAt.IsZero() {
+			meta = append(meta, "completed:"+formatTime(t.CompletedAt))
+		}
+
+		indent := ""
+		if l.isSubtask(t) {
+			indent = "  "
+		}
+
+		_, err := fmt.Fprintf(w, "%s- %s %s <!-- %s -->\n", indent, box, t.Task, strings.Join(meta, " "))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var (
+	markdownItemRe = regexp.MustCompile(`^(\s*)[-*+] \[([ xX])\] (.*)$`)
+	markdownMetaRe = regexp.MustCompile(`\s*<!--(.*)-->\s*$`)
+)
+
+func importMarkdown(r io.Reader) ([]item, error) {
+	items := []item{}
+	s := bufio.NewScanner(r)
+	parent := 0
+
+	// Items get their line number as ID so that indented items can
+	// reference the top level item above them as parent.
+	for n := 1; s.Scan(); n++ {
+		m := markdownItemRe.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+
+		t := item{ID: n, Done: m[2] != " "}
+		text := m[3]
+
+		if m[1] == "" {
+			parent = n
+		} else {
+			t.Parent = parent
+		}
+
+		if meta := markdownMetaRe.FindStringSubmatchIndex(text); meta != nil {
+			if err := t.setMarkdownMeta(text[meta[2]:meta[3]]); err != nil {
+				return nil, fmt.Errorf("Line %d: %w", n, err)
+			}
+
+			text = text[:meta[0]]
+		}
+
+		t.Task = strings.TrimSpace(text)
+		if t.Task == "" {
+			return nil, fmt.Errorf("Line %d: %w", n, ErrBlankTask)
+		}
+
+		items = append(items, t)
+	}
+
+	return items, s.Err()
+}
+
+func (t *item) setMarkdownMeta(meta string) error {
+	for _, field := range strings.Fields(meta) {
+		key, value, _ := strings.Cut(field, ":")
+
+		var err error
+		switch key {
+		case "created":
+			t.CreatedAt, err = parseTime(value)
+		case "completed":
+			t.CompletedAt, err = parseTime(value)
+		case "due":
+			t.Due, err = parseTime(value)
+		case "priority":
+			t.Priority, err = ParsePriority(value)
+		case "tags":
+			WithTags(strings.Split(value, ",")...)(t)
+		case "repeat":
+			t.Repeat, err = decodeRule(value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,3 +1,20 @@
 module github.com/ZeroBl21/cli/ch02
 
 go 1.23.1
+
+require golang.org/x/sys v0.18.0
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.24
+	github.com/mum4k/termdash v0.20.0
+)
+
+require (
+	github.com/gdamore/encoding v1.0.0 // indirect
+	github.com/gdamore/tcell/v2 v2.7.4 // indirect
+	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
+	github.com/mattn/go-runewidth v0.0.15 // indirect
+	github.com/rivo/uniseg v0.4.3 // indirect
+	golang.org/x/term v0.17.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
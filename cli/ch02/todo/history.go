@@ -0,0 +1,383 @@
+package todo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+type Op string
+
+const (
+	OpAdd      Op = "add"
+	OpComplete Op = "complete"
+	OpReopen   Op = "reopen"
+	OpDelete   Op = "delete"
+	OpEdit     Op = "edit"
+	OpUndo     Op = "undo"
+	OpRedo     Op = "redo"
+)
+
+var (
+	ErrNothingToUndo = errors.New("Nothing to undo")
+	ErrNothingToRedo = errors.New("Nothing to redo")
+	ErrItemChanged   = errors.New("Item changed since the history was recorded")
+)
+
+// Change is one entry of the history log. Before and After hold the item
+// as it was around the mutation and Index its position in the list.
+// Related holds the changes the mutation made to other items, such as the
+// subtasks of a deleted item or the next occurrence of a recurring one.
+// Undo and redo entries point at the change they revert or replay through
+// Ref.
+type Change struct {
+	Seq     int       `json:",omitempty"`
+	At      time.Time `json:",omitempty"`
+	Op      Op        `json:",omitempty"`
+	ID      int
+	Index   int
+	Ref     int      `json:",omitempty"`
+	Before  *item    `json:",omitempty"`
+	After   *item    `json:",omitempty"`
+	Related []Change `json:",omitempty"`
+}
+
+// NewChange describes the mutation op made to the item with the given ID
+// by comparing the list before and after it.
+func NewChange(op Op, id int, before, after List) Change {
+	c := diffItem(id, before, after)
+	c.At = time.Now()
+	c.Op = op
+
+	for _, t := range before {
+		if r := diffItem(t.ID, before, after); t.ID != id && r.changed() {
+			c.Related = append(c.Related, r)
+		}
+	}
+
+	for _, t := range after {
+		if _, err := before.Find(t.ID); err != nil && t.ID != id {
+			c.Related = append(c.Related, diffItem(t.ID, before, after))
+		}
+	}
+
+	return c
+}
+
+func diffItem(id int, before, after List) Change {
+	c := Change{ID: id}
+
+	if idx, err := before.Find(id); err == nil {
+		c.Before = &before[idx]
+		c.Index = idx
+	}
+
+	if idx, err := after.Find(id); err == nil {
+		c.After = &after[idx]
+		c.Index = idx
+	}
+
+	return c
+}
+
+func (c Change) changed() bool {
+	if c.Before == nil || c.After == nil {
+		return c.Before != c.After
+	}
+
+	return !sameItem(*c.Before, *c.After)
+}
+
+// sameItem compares items as they are stored, so times read back from a
+// file or a database match the ones recorded. Removing an item updates
+// MaxID on all the others, which is not a change of theirs.
+func sameItem(a, b item) bool {
+	a.MaxID, b.MaxID = 0, 0
+
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(ja, jb)
+}
+
+func (c Change) String() string {
+	task := ""
+	switch {
+	case c.After != nil:
+		task = c.After.Task
+	case c.Before != nil:
+		task = c.Before.Task
+	}
+
+	if c.Op == OpUndo || c.Op == OpRedo {
+		return fmt.Sprintf("%s %-8s #%d: %s (change %d)",
+			c.At.Format(time.DateTime), c.Op, c.ID, task, c.Ref)
+	}
+
+	return fmt.Sprintf("%s %-8s #%d: %s", c.At.Format(time.DateTime), c.Op, c.ID, task)
+}
+
+// History is an append-only log of the changes made to a list, kept as one
+// JSON document per line.
+type History struct {
+	filename string
+}
+
+func NewHistory(filename string) *History {
+	return &History{
+		filename: filename,
+	}
+}
+
+func (h *History) Entries() ([]Change, error) {
+	unlock, err := lock(h.filename, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return h.read()
+}
+
+func (h *History) read() ([]Change, error) {
+	f, err := os.Open(h.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []Change{}
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+
+	for s.Scan() {
+		var c Change
+		if err := json.Unmarshal(s.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("Invalid history entry %d: %w", len(entries)+1, err)
+		}
+
+		entries = append(entries, c)
+	}
+
+	return entries, s.Err()
+}
+
+func (h *History) Record(c Change) error {
+	unlock, err := lock(h.filename, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := h.read()
+	if err != nil {
+		return err
+	}
+	c.Seq = len(entries) + 1
+
+	js, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(js, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Update applies fn to the list through r, as the package level Update
+// does, and records the change it made. fn returns the ID of the item it
+// changed. A nil History records nothing.
+func (h *History) Update(r Repository, l *List, op Op, fn func(*List) (int, error)) error {
+	var change Change
+
+	err := Update(r, l, func(l *List) error {
+		before := slices.Clone(*l)
+
+		id, err := fn(l)
+		if err != nil {
+			return err
+		}
+
+		change = NewChange(op, id, before, *l)
+		return nil
+	})
+	if err != nil || h == nil {
+		return err
+	}
+
+	return h.Record(change)
+}
+
+// stacks replays the log and returns the changes that can be undone and
+// redone, most recent last.
+func stacks(entries []Change) (done, undone []Change) {
+	bySeq := map[int]Change{}
+
+	for _, c := range entries {
+		bySeq[c.Seq] = c
+
+		switch c.Op {
+		case OpUndo:
+			if n := len(done); n > 0 && done[n-1].Seq == c.Ref {
+				done = done[:n-1]
+				undone = append(undone, bySeq[c.Ref])
+			}
+		case OpRedo:
+			if n := len(undone); n > 0 && undone[n-1].Seq == c.Ref {
+				undone = undone[:n-1]
+				done = append(done, bySeq[c.Ref])
+			}
+		default:
+			done = append(done, c)
+			undone = nil
+		}
+	}
+
+	return done, undone
+}
+
+// Undo reverts the last change on l and returns the entry to Record once
+// the list is saved.
+func (h *History) Undo(l *List) (Change, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return Change{}, err
+	}
+
+	done, _ := stacks(entries)
+	if len(done) == 0 {
+		return Change{}, ErrNothingToUndo
+	}
+
+	c := done[len(done)-1]
+	if err := l.replay(c, true); err != nil {
+		return Change{}, fmt.Errorf("Cannot undo change %d: %w", c.Seq, err)
+	}
+
+	return Change{At: time.Now(), Op: OpUndo, ID: c.ID, Index: c.Index, Ref: c.Seq, Before: c.After, After: c.Before}, nil
+}
+
+// Redo replays the last undone change on l and returns the entry to
+// Record once the list is saved.
+func (h *History) Redo(l *List) (Change, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return Change{}, err
+	}
+
+	_, undone := stacks(entries)
+	if len(undone) == 0 {
+		return Change{}, ErrNothingToRedo
+	}
+
+	c := undone[len(undone)-1]
+	if err := l.replay(c, false); err != nil {
+		return Change{}, fmt.Errorf("Cannot redo change %d: %w", c.Seq, err)
+	}
+
+	return Change{At: time.Now(), Op: OpRedo, ID: c.ID, Index: c.Index, Ref: c.Seq, Before: c.Before, After: c.After}, nil
+}
+
+// replay applies c and its related changes to l, or reverts them when
+// revert is set. Removals and edits go first, then insertions in position
+// order, so restored items land where they were.
+func (l *List) replay(c Change, revert bool) error {
+	changes := append([]Change{c}, c.Related...)
+
+	if revert {
+		for i, r := range changes {
+			changes[i].Before, changes[i].After = r.After, r.Before
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		aInsert, bInsert := a.Before == nil, b.Before == nil
+		switch {
+		case aInsert && bInsert:
+			return a.Index - b.Index
+		case aInsert:
+			return 1
+		case bInsert:
+			return -1
+		}
+
+		return 0
+	})
+
+	for _, r := range changes {
+		err := l.apply(r.Before, r.After, r.Index)
+		if err != nil && (r.ID == c.ID || !errors.Is(err, ErrNotExists)) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply turns the item from into to: a nil from inserts to at index, a nil
+// to removes from, anything else replaces the item in place. An item that
+// no longer matches from was changed without being recorded in the history,
+// so apply returns ErrItemChanged instead of overwriting it.
+func (l *List) apply(from, to *item, index int) error {
+	switch {
+	case from == nil && to != nil:
+		if _, err := l.Find(to.ID); err == nil {
+			return fmt.Errorf("Item %d already exists", to.ID)
+		}
+
+		index = min(max(index, 0), len(*l))
+		*l = slices.Insert(*l, index, *to)
+
+	case from != nil && to == nil:
+		idx, err := l.Find(from.ID)
+		if err != nil {
+			return err
+		}
+
+		if !sameItem((*l)[idx], *from) {
+			return fmt.Errorf("%w: %d", ErrItemChanged, from.ID)
+		}
+
+		l.remove(func(t item) bool {
+			return t.ID == from.ID
+		})
+
+	case from != nil && to != nil:
+		idx, err := l.Find(from.ID)
+		if err != nil {
+			return err
+		}
+
+		if !sameItem((*l)[idx], *from) {
+			return fmt.Errorf("%w: %d", ErrItemChanged, from.ID)
+		}
+
+		(*l)[idx] = *to
+	}
+
+	return nil
+}
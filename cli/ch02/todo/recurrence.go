@@ -0,0 +1,344 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRecurrence = errors.New("Invalid recurrence rule")
+
+const (
+	RepeatDaily   = "daily"
+	RepeatWeekly  = "weekly"
+	RepeatMonthly = "monthly"
+	RepeatCron    = "cron"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Recurrence describes when a task repeats. Rules are written as "daily",
+// "weekly", "weekly:mon,fri", "monthly", "monthly:31" or
+// "cron:<5 fields>". Next dates follow the schedule from the previous due
+// date, unless the rule starts with "after:", which counts from the
+// completion time instead.
+type Recurrence struct {
+	Every          string
+	Weekdays       []time.Weekday
+	FromCompletion bool
+	// Day is the day of the month monthly rules land on, or the last day
+	// of shorter months. Zero keeps the day of the previous date.
+	Day int
+
+	cron *cronSchedule
+	spec string
+}
+
+func ParseRecurrence(rule string) (Recurrence, error) {
+	r := Recurrence{}
+
+	rule = strings.TrimSpace(rule)
+	if after, found := strings.CutPrefix(strings.ToLower(rule), "after:"); found {
+		r.FromCompletion = true
+		rule = rule[len(rule)-len(after):]
+	}
+
+	every, args, _ := strings.Cut(rule, ":")
+	r.Every = strings.ToLower(strings.TrimSpace(every))
+
+	switch r.Every {
+	case RepeatDaily:
+		if args != "" {
+			return Recurrence{}, fmt.Errorf("%w: %q", ErrInvalidRecurrence, rule)
+		}
+
+	case RepeatMonthly:
+		if args == "" {
+			break
+		}
+
+		day, err := strconv.Atoi(strings.TrimSpace(args))
+		if err != nil || day < 1 || day > 31 {
+			return Recurrence{}, fmt.Errorf("%w: invalid day %q", ErrInvalidRecurrence, args)
+		}
+		r.Day = day
+
+	case RepeatWeekly:
+		for _, d := range strings.Split(args, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "" {
+				continue
+			}
+
+			wd, ok := weekdays[d[:min(len(d), 3)]]
+			if !ok {
+				return Recurrence{}, fmt.Errorf("%w: unknown weekday %q", ErrInvalidRecurrence, d)
+			}
+
+			if !slices.Contains(r.Weekdays, wd) {
+				r.Weekdays = append(r.Weekdays, wd)
+			}
+		}
+		slices.Sort(r.Weekdays)
+
+	case RepeatCron:
+		c, err := parseCron(args)
+		if err != nil {
+			return Recurrence{}, fmt.Errorf("%w: %q: %v", ErrInvalidRecurrence, rule, err)
+		}
+		r.cron = c
+		r.spec = strings.Join(strings.Fields(args), " ")
+
+	default:
+		return Recurrence{}, fmt.Errorf("%w: %q", ErrInvalidRecurrence, rule)
+	}
+
+	return r, nil
+}
+
+func (r Recurrence) String() string {
+	rule := r.Every
+
+	switch r.Every {
+	case RepeatWeekly:
+		if len(r.Weekdays) > 0 {
+			days := []string{}
+			for _, wd := range r.Weekdays {
+				days = append(days, strings.ToLower(wd.String()[:3]))
+			}
+			rule += ":" + strings.Join(days, ",")
+		}
+	case RepeatMonthly:
+		if r.Day > 0 {
+			rule += ":" + strconv.Itoa(r.Day)
+		}
+	case RepeatCron:
+		rule += ":" + r.spec
+	}
+
+	if r.FromCompletion {
+		rule = "after:" + rule
+	}
+
+	return rule
+}
+
+// Next returns the first occurrence strictly after from. Daily, weekly
+// and monthly rules land on midnight; cron rules keep minute precision.
+func (r Recurrence) Next(from time.Time) time.Time {
+	y, m, d := from.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, from.Location())
+
+	switch r.Every {
+	case RepeatDaily:
+		return day.AddDate(0, 0, 1)
+
+	case RepeatWeekly:
+		if len(r.Weekdays) == 0 {
+			return day.AddDate(0, 0, 7)
+		}
+
+		for i := 1; i <= 7; i++ {
+			next := day.AddDate(0, 0, i)
+			if slices.Contains(r.Weekdays, next.Weekday()) {
+				return next
+			}
+		}
+
+	case RepeatMonthly:
+		if r.Day == 0 {
+			return monthDay(y, m+1, d, from.Location())
+		}
+
+		if next := monthDay(y, m, r.Day, from.Location()); next.After(day) {
+			return next
+		}
+
+		return monthDay(y, m+1, r.Day, from.Location())
+
+	case RepeatCron:
+		return r.cron.next(from)
+	}
+
+	return time.Time{}
+}
+
+// monthDay returns midnight of day d of month m, or of its last day when
+// the month is shorter.
+func monthDay(y int, m time.Month, d int, loc *time.Location) time.Time {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	last := first.AddDate(0, 1, -1).Day()
+
+	return first.AddDate(0, 0, min(d, last)-1)
+}
+
+// nextDue returns the due date of the occurrence following a task with
+// the given due date, completed at done. Schedule based rules skip the
+// occurrences missed before the completion day, or before the completion
+// time for cron rules.
+func (r Recurrence) nextDue(due, done time.Time) time.Time {
+	if r.FromCompletion || due.IsZero() {
+		return r.Next(done)
+	}
+
+	threshold := done
+	if r.Every != RepeatCron {
+		y, m, d := done.Date()
+		threshold = time.Date(y, m, d, 0, 0, 0, 0, done.Location())
+	}
+
+	next := r.Next(due)
+	for !next.IsZero() && !next.After(threshold) {
+		next = r.Next(next)
+	}
+
+	return next
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week, 0 and 7 are Sunday
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	anyDOM, anyDOW bool
+}
+
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(cronFields), len(fields))
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, f := range fields {
+		set, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDOM: fields[2] == "*",
+		anyDOW: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := bounds.min, bounds.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+
+			var errA, errB error
+			lo, errA = strconv.Atoi(a)
+			hi, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+
+			lo = n
+			if !hasStep {
+				hi = n
+			}
+		}
+
+		if lo < bounds.min || hi > bounds.max || lo > hi {
+			return 0, fmt.Errorf("value out of range %q", part)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+
+	return set, nil
+}
+
+func (c *cronSchedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+
+	switch {
+	case c.anyDOM && c.anyDOW:
+		return true
+	case c.anyDOM:
+		return dow
+	case c.anyDOW:
+		return dom
+	}
+
+	return dom || dow
+}
+
+func (c *cronSchedule) next(from time.Time) time.Time {
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
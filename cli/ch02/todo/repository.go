@@ -0,0 +1,33 @@
+package todo
+
+import "errors"
+
+const maxUpdateAttempts = 5
+
+var ErrConflict = errors.New("List changed since it was loaded")
+
+// Repository loads and stores a List. Save must return ErrConflict when
+// the stored list changed after the last Get.
+type Repository interface {
+	Get(l *List) error
+	Save(l *List) error
+}
+
+// Update loads the list from r, applies fn and saves the result. When
+// someone else saves in between, the list is reloaded and fn applied again.
+func Update(r Repository, l *List, fn func(*List) error) error {
+	for attempt := 1; ; attempt++ {
+		if err := r.Get(l); err != nil {
+			return err
+		}
+
+		if err := fn(l); err != nil {
+			return err
+		}
+
+		err := r.Save(l)
+		if !errors.Is(err, ErrConflict) || attempt == maxUpdateAttempts {
+			return err
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+)
+
+// httpRepo keeps the list on a remote todo server. The server answers GET
+// with the whole list and an ETag, and accepts PUT with a matching If-Match.
+type httpRepo struct {
+	url    string
+	client *http.Client
+	sync.Mutex
+
+	etag string
+}
+
+func NewHTTPRepo(url string) *httpRepo {
+	return &httpRepo{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (r *httpRepo) Get(l *todo.List) error {
+	r.Lock()
+	defer r.Unlock()
+
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	ls := todo.List{}
+	if err := json.NewDecoder(resp.Body).Decode(&ls); err != nil {
+		return err
+	}
+
+	*l = ls
+	r.etag = resp.Header.Get("ETag")
+
+	return nil
+}
+
+func (r *httpRepo) Save(l *todo.List) error {
+	r.Lock()
+	defer r.Unlock()
+
+	js, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, r.url, bytes.NewReader(js))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if r.etag != "" {
+		req.Header.Set("If-Match", r.etag)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return fmt.Errorf("%w: %s", todo.ErrConflict, r.url)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return responseError(resp)
+	}
+
+	r.etag = resp.Header.Get("ETag")
+
+	return nil
+}
+
+func responseError(resp *http.Response) error {
+	msg, err := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("Unexpected response from server: %s: %s",
+		resp.Status, bytes.TrimSpace(msg))
+}
@@ -0,0 +1,267 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+	"github.com/ZeroBl21/cli/ch02/repository"
+)
+
+// listServer is a minimal stand-in for the todo server protocol used by
+// the HTTP repository.
+type listServer struct {
+	sync.Mutex
+	data    []byte
+	version int
+}
+
+func (s *listServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	etag := fmt.Sprintf("%q", fmt.Sprint(s.version))
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("ETag", etag)
+		if s.data == nil {
+			io.WriteString(w, "[]")
+			return
+		}
+		w.Write(s.data)
+
+	case http.MethodPut:
+		if m := r.Header.Get("If-Match"); m != "" && m != etag {
+			http.Error(w, "stale list", http.StatusPreconditionFailed)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil || !json.Valid(data) {
+			http.Error(w, "invalid list", http.StatusBadRequest)
+			return
+		}
+
+		s.data = data
+		s.version++
+		w.Header().Set("ETag", fmt.Sprintf("%q", fmt.Sprint(s.version)))
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func getRepos(t *testing.T) map[string]func() todo.Repository {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	srv := httptest.NewServer(&listServer{})
+	t.Cleanup(srv.Close)
+
+	return map[string]func() todo.Repository{
+		"File": func() todo.Repository {
+			return todo.NewFileStore(filepath.Join(dir, "todo.json"))
+		},
+		"SQLite": func() todo.Repository {
+			repo, err := repository.NewSQLiteRepo(filepath.Join(dir, "todo.db"), todo.DefaultList)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return repo
+		},
+		"HTTP": func() todo.Repository {
+			return repository.NewHTTPRepo(srv.URL + "/todo")
+		},
+	}
+}
+
+func TestSaveGet(t *testing.T) {
+	due := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.Local)
+
+	for name, newRepo := range getRepos(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+
+			l1 := todo.List{}
+			if err := repo.Get(&l1); err != nil {
+				t.Fatal(err)
+			}
+
+			l1.Add("Task 1", todo.WithPriority(todo.PriorityHigh), todo.WithDue(due))
+			l1.Add("Task 2", todo.WithTags("work", "home"))
+			if err := l1.Complete(2); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := repo.Save(&l1); err != nil {
+				t.Fatal(err)
+			}
+
+			l2 := todo.List{}
+			if err := newRepo().Get(&l2); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(l2) != 2 {
+				t.Fatalf("Expected %d tasks, got %d instead.", 2, len(l2))
+			}
+
+			if l1.String() != l2.String() {
+				t.Errorf("Expected %q, got %q instead.", l1.String(), l2.String())
+			}
+
+			if !l2[0].Due.Equal(due) || l2[1].CompletedAt.IsZero() {
+				t.Errorf("Dates were not preserved: %+v", l2)
+			}
+		})
+	}
+}
+
+func TestDeletedIDsNotReused(t *testing.T) {
+	for name, newRepo := range getRepos(t) {
+		t.Run(name, func(t *testing.T) {
+			steps := []func(l *todo.List) error{
+				func(l *todo.List) error {
+					l.Add("one")
+					l.Add("two")
+					return nil
+				},
+				func(l *todo.List) error {
+					return l.Delete(2)
+				},
+				func(l *todo.List) error {
+					l.Add("three")
+					return nil
+				},
+			}
+
+			for _, step := range steps {
+				l := todo.List{}
+				if err := todo.Update(newRepo(), &l, step); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l := todo.List{}
+			if err := newRepo().Get(&l); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := "[ ] 1: one\n[ ] 3: three\n"
+			if l.String() != expected {
+				t.Errorf("Expected %q, got %q instead.", expected, l.String())
+			}
+		})
+	}
+}
+
+func TestSaveConflict(t *testing.T) {
+	for name, newRepo := range getRepos(t) {
+		t.Run(name, func(t *testing.T) {
+			r1, r2 := newRepo(), newRepo()
+			l1, l2 := todo.List{}, todo.List{}
+
+			if err := r1.Get(&l1); err != nil {
+				t.Fatal(err)
+			}
+			if err := r2.Get(&l2); err != nil {
+				t.Fatal(err)
+			}
+
+			l1.Add("Task 1")
+			if err := r1.Save(&l1); err != nil {
+				t.Fatal(err)
+			}
+
+			l2.Add("Task 2")
+			if err := r2.Save(&l2); !errors.Is(err, todo.ErrConflict) {
+				t.Fatalf("Expected error %q, got %q instead.", todo.ErrConflict, err)
+			}
+
+			err := todo.Update(r2, &l2, func(l *todo.List) error {
+				l.Add("Task 2")
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(l2) != 2 {
+				t.Errorf("Expected %d tasks, got %d instead.", 2, len(l2))
+			}
+		})
+	}
+}
+
+func TestNamedLists(t *testing.T) {
+	dir := t.TempDir()
+
+	newRepos := map[string]func(list string) todo.Repository{
+		"File": func(list string) todo.Repository {
+			return todo.NewNamedFileStore(filepath.Join(dir, "todo.json"), list)
+		},
+		"SQLite": func(list string) todo.Repository {
+			repo, err := repository.NewSQLiteRepo(filepath.Join(dir, "todo.db"), list)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return repo
+		},
+	}
+
+	for name, newRepo := range newRepos {
+		t.Run(name, func(t *testing.T) {
+			home, work := todo.List{}, todo.List{}
+
+			home.Add("Groceries")
+			work.Add("Report")
+			if err := work.AddSubtask(1, "Draft"); err != nil {
+				t.Fatal(err)
+			}
+
+			err := todo.Update(newRepo(todo.DefaultList), &todo.List{}, func(l *todo.List) error {
+				*l = home
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = todo.Update(newRepo("work"), &todo.List{}, func(l *todo.List) error {
+				*l = work
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for list, expected := range map[string]string{
+				todo.DefaultList: "[ ] 1: Groceries\n",
+				"work":           "[ ] 1: Report\n  [ ] 2: Draft\n",
+				"empty":          "",
+			} {
+				l := todo.List{}
+				if err := newRepo(list).Get(&l); err != nil {
+					t.Fatal(err)
+				}
+
+				if l.String() != expected {
+					t.Errorf("Expected list %q to be %q, got %q instead.", list, expected, l.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// migrations are applied in order on open, tracked by PRAGMA user_version.
+var migrations = []string{
+	`
+	CREATE TABLE IF NOT EXISTS "item" (
+		"position" INTEGER NOT NULL,
+		"task" TEXT NOT NULL,
+		"done" INTEGER DEFAULT 0,
+		"created_at" DATETIME NOT NULL,
+		"completed_at" DATETIME NOT NULL,
+		"priority" INTEGER DEFAULT 0,
+		"due" DATETIME NOT NULL,
+		"tags" TEXT DEFAULT '[]',
+		PRIMARY KEY("position")
+	);
+	CREATE TABLE IF NOT EXISTS "meta" (
+		"version" INTEGER NOT NULL
+	);
+	INSERT INTO meta VALUES(0);`,
+	`
+	ALTER TABLE item ADD COLUMN "id" INTEGER NOT NULL DEFAULT 0;
+	UPDATE item SET id = position WHERE id = 0;`,
+	`
+	ALTER TABLE item ADD COLUMN "repeat" TEXT NOT NULL DEFAULT '';`,
+	`
+	CREATE TABLE "item_lists" (
+		"list" TEXT NOT NULL DEFAULT 'default',
+		"position" INTEGER NOT NULL,
+		"id" INTEGER NOT NULL DEFAULT 0,
+		"parent" INTEGER NOT NULL DEFAULT 0,
+		"task" TEXT NOT NULL,
+		"done" INTEGER DEFAULT 0,
+		"created_at" DATETIME NOT NULL,
+		"completed_at" DATETIME NOT NULL,
+		"priority" INTEGER DEFAULT 0,
+		"due" DATETIME NOT NULL,
+		"tags" TEXT DEFAULT '[]',
+		"repeat" TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY("list", "position")
+	);
+	INSERT INTO item_lists(position, id, task, done, created_at, completed_at, priority, due, tags, repeat)
+	SELECT position, id, task, done, created_at, completed_at, priority, due, tags, repeat FROM item;
+	DROP TABLE item;
+	ALTER TABLE item_lists RENAME TO item;`,
+	`
+	ALTER TABLE item ADD COLUMN "max_id" INTEGER NOT NULL DEFAULT 0;`,
+}
+
+type dbRepo struct {
+	db   *sql.DB
+	list string
+	sync.RWMutex
+
+	version int64
+	loaded  bool
+}
+
+// NewSQLiteRepo opens the named list stored in dbfile, creating or
+// upgrading the schema as needed. An empty name selects todo.DefaultList.
+func NewSQLiteRepo(dbfile, list string) (*dbRepo, error) {
+	if list == "" {
+		list = todo.DefaultList
+	}
+
+	dsn := fmt.Sprintf("file:%s?_txlock=immediate&_busy_timeout=5000", dbfile)
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetConnMaxIdleTime(30 * time.Minute)
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
+	return &dbRepo{
+		db:   db,
+		list: list,
+	}, nil
+}
+
+func migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return err
+	}
+
+	for v := current; v < len(migrations); v++ {
+		if _, err := tx.Exec(migrations[v]); err != nil {
+			return fmt.Errorf("Migration %d: %w", v+1, err)
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(migrations))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *dbRepo) Get(l *todo.List) error {
+	r.Lock()
+	defer r.Unlock()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int64
+	if err := tx.QueryRow("SELECT version FROM meta").Scan(&version); err != nil {
+		return err
+	}
+
+	query := `
+	SELECT id, parent, task, done, created_at, completed_at, priority, due, tags, repeat, max_id
+	FROM item WHERE list = ? ORDER BY position`
+
+	rows, err := tx.Query(query, r.list)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ls := todo.List{}
+
+	for rows.Next() {
+		ls = append(ls, todo.List{{}}...)
+		i := &ls[len(ls)-1]
+
+		var tags string
+		err := rows.Scan(
+			&i.ID,
+			&i.Parent,
+			&i.Task,
+			&i.Done,
+			&i.CreatedAt,
+			&i.CompletedAt,
+			&i.Priority,
+			&i.Due,
+			&tags,
+			&i.Repeat,
+			&i.MaxID,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal([]byte(tags), &i.Tags); err != nil {
+			return err
+		}
+
+		i.CreatedAt = i.CreatedAt.Local()
+		i.CompletedAt = i.CompletedAt.Local()
+		i.Due = i.Due.Local()
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ls.AssignIDs()
+
+	*l = ls
+	r.version = version
+	r.loaded = true
+
+	return nil
+}
+
+func (r *dbRepo) Save(l *todo.List) error {
+	r.Lock()
+	defer r.Unlock()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int64
+	if err := tx.QueryRow("SELECT version FROM meta").Scan(&version); err != nil {
+		return err
+	}
+
+	if r.loaded && version != r.version {
+		return fmt.Errorf("%w: version %d, loaded %d", todo.ErrConflict, version, r.version)
+	}
+
+	if _, err := tx.Exec("DELETE FROM item WHERE list = ?", r.list); err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO item(list, position, id, parent, task, done, created_at, completed_at, priority, due, tags, repeat, max_id)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for pos, i := range *l {
+		tags, err := json.Marshal(i.Tags)
+		if err != nil {
+			return err
+		}
+
+		args := []any{
+			r.list,
+			pos + 1,
+			i.ID,
+			i.Parent,
+			i.Task,
+			i.Done,
+			i.CreatedAt,
+			i.CompletedAt,
+			i.Priority,
+			i.Due,
+			string(tags),
+			i.Repeat,
+			i.MaxID,
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE meta SET version = ?", version+1); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	r.version = version + 1
+	r.loaded = true
+
+	return nil
+}
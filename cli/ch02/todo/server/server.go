@@ -0,0 +1,416 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+)
+
+const maxBodySize = 1 << 20
+
+type newItem struct {
+	Task     string   `json:"task"`
+	Priority string   `json:"priority"`
+	Due      string   `json:"due"`
+	Tags     []string `json:"tags"`
+	Repeat   string   `json:"repeat"`
+	Parent   int      `json:"parent"`
+}
+
+type itemPatch struct {
+	Done *bool   `json:"done"`
+	Task *string `json:"task"`
+}
+
+// Opener returns the repository holding the named list and the history
+// its changes are recorded in, nil to keep none.
+type Opener func(list string) (todo.Repository, *todo.History, error)
+
+// store is an opened list.
+type store struct {
+	repo todo.Repository
+	hist *todo.History
+}
+
+type server struct {
+	open   Opener
+	stores map[string]store
+	sync.Mutex
+}
+
+// NewHandler exposes the lists returned by open as a JSON API. Every route
+// takes a ?list=name query parameter selecting the list, todo.DefaultList
+// when omitted:
+//
+//	GET    /todo          whole list, ?pending=true for pending items only
+//	PUT    /todo          replace the list, guarded by If-Match
+//	POST   /todo          add an item, a subtask when it names a parent
+//	PATCH  /todo/{id}     complete or reopen an item or edit its task,
+//	                      ?cascade=true completes its pending subtasks too
+//	DELETE /todo/{id}     delete an item along with its subtasks
+//
+// Adding, completing, editing and deleting items is recorded in the history
+// of the list, so the CLI can undo it. Replacing the list is not, and undo
+// refuses to overwrite the items it changed.
+func NewHandler(open Opener) http.Handler {
+	s := &server{open: open, stores: map[string]store{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /todo", s.getList)
+	mux.HandleFunc("PUT /todo", s.putList)
+	mux.HandleFunc("POST /todo", s.addItem)
+	mux.HandleFunc("PATCH /todo/{id}", s.patchItem)
+	mux.HandleFunc("DELETE /todo/{id}", s.deleteItem)
+
+	return mux
+}
+
+func (s *server) getList(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	st, err := s.store(r)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	l := todo.List{}
+	if err := st.repo.Get(&l); err != nil {
+		replyError(w, err)
+		return
+	}
+
+	etag, err := listETag(l)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+
+	if pending, _ := strconv.ParseBool(r.URL.Query().Get("pending")); pending {
+		l = l.Filter(todo.IsPending)
+	}
+
+	replyJSON(w, http.StatusOK, l)
+}
+
+func (s *server) putList(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	l := todo.List{}
+	if err := decodeBody(w, r, &l); err != nil {
+		replyError(w, err)
+		return
+	}
+
+	st, err := s.store(r)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	current := todo.List{}
+	if err := st.repo.Get(&current); err != nil {
+		replyError(w, err)
+		return
+	}
+
+	etag, err := listETag(current)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	if match := r.Header.Get("If-Match"); match != "" && match != etag {
+		http.Error(w, "List changed since it was loaded", http.StatusPreconditionFailed)
+		return
+	}
+
+	if err := st.repo.Save(&l); err != nil {
+		replyError(w, err)
+		return
+	}
+
+	etag, err = listETag(l)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) addItem(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	var ni newItem
+	if err := decodeBody(w, r, &ni); err != nil {
+		replyError(w, err)
+		return
+	}
+
+	if ni.Task == "" {
+		http.Error(w, "Task cannot be blank", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := ni.options()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	st, err := s.store(r)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	l := todo.List{}
+	err = st.hist.Update(st.repo, &l, todo.OpAdd, func(l *todo.List) (int, error) {
+		if ni.Parent != 0 {
+			if err := l.AddSubtask(ni.Parent, ni.Task, opts...); err != nil {
+				return 0, err
+			}
+		} else {
+			l.Add(ni.Task, opts...)
+		}
+
+		return (*l)[len(*l)-1].ID, nil
+	})
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	added := l[len(l)-1]
+
+	w.Header().Set("Location", fmt.Sprintf("/todo/%d", added.ID))
+	replyJSON(w, http.StatusCreated, added)
+}
+
+func (s *server) patchItem(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+		return
+	}
+
+	var p itemPatch
+	if err := decodeBody(w, r, &p); err != nil {
+		replyError(w, err)
+		return
+	}
+
+	if p.Done == nil && p.Task == nil {
+		http.Error(w, `Expected {"done": true|false} or {"task": "..."}`, http.StatusBadRequest)
+		return
+	}
+
+	st, err := s.store(r)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	cascade, _ := strconv.ParseBool(r.URL.Query().Get("cascade"))
+
+	op := todo.OpEdit
+	switch {
+	case p.Done != nil && *p.Done:
+		op = todo.OpComplete
+	case p.Done != nil:
+		op = todo.OpReopen
+	}
+
+	l := todo.List{}
+	err = st.hist.Update(st.repo, &l, op, func(l *todo.List) (int, error) {
+		if p.Task != nil {
+			if err := l.Edit(id, *p.Task); err != nil {
+				return id, err
+			}
+		}
+
+		switch {
+		case op == todo.OpComplete && cascade:
+			return id, l.CompleteCascade(id)
+		case op == todo.OpComplete:
+			return id, l.Complete(id)
+		case op == todo.OpReopen:
+			return id, l.Reopen(id)
+		}
+
+		return id, nil
+	})
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	idx, err := l.Find(id)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	replyJSON(w, http.StatusOK, l[idx])
+}
+
+func (s *server) deleteItem(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+		return
+	}
+
+	st, err := s.store(r)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	l := todo.List{}
+	err = st.hist.Update(st.repo, &l, todo.OpDelete, func(l *todo.List) (int, error) {
+		return id, l.Delete(id)
+	})
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// store returns the repository and history of the list named by the
+// request, opening them on first use. Callers hold the server lock.
+func (s *server) store(r *http.Request) (store, error) {
+	name := r.URL.Query().Get("list")
+	if name == "" {
+		name = todo.DefaultList
+	}
+
+	if st, ok := s.stores[name]; ok {
+		return st, nil
+	}
+
+	repo, hist, err := s.open(name)
+	if err != nil {
+		return store{}, err
+	}
+
+	st := store{repo: repo, hist: hist}
+	s.stores[name] = st
+
+	return st, nil
+}
+
+func (ni newItem) options() ([]todo.ItemOption, error) {
+	opts := []todo.ItemOption{todo.WithTags(ni.Tags...)}
+
+	if ni.Priority != "" {
+		p, err := todo.ParsePriority(ni.Priority)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, todo.WithPriority(p))
+	}
+
+	if ni.Due != "" {
+		due, err := time.ParseInLocation(time.DateOnly, ni.Due, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid due date %q", ni.Due)
+		}
+		opts = append(opts, todo.WithDue(due))
+	}
+
+	if ni.Repeat != "" {
+		r, err := todo.ParseRecurrence(ni.Repeat)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, todo.WithRecurrence(r))
+	}
+
+	return opts, nil
+}
+
+func listETag(l todo.List) (string, error) {
+	js, err := json.Marshal(l)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`"%x"`, sha256.Sum256(js)), nil
+}
+
+type badRequestError struct {
+	cause error
+}
+
+func (e *badRequestError) Error() string {
+	return fmt.Sprintf("Invalid request body: %v", e.cause)
+}
+
+func (e *badRequestError) Unwrap() error {
+	return e.cause
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	defer func(body io.ReadCloser) {
+		io.Copy(io.Discard, body)
+		body.Close()
+	}(r.Body)
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(v); err != nil {
+		return &badRequestError{cause: err}
+	}
+
+	return nil
+}
+
+func replyJSON(w http.ResponseWriter, status int, v any) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+func replyError(w http.ResponseWriter, err error) {
+	var badRequest *badRequestError
+
+	switch {
+	case errors.As(err, &badRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, todo.ErrBlankTask), errors.Is(err, todo.ErrNestedSubtask):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, todo.ErrNotExists):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, todo.ErrConflict), errors.Is(err, todo.ErrPendingSubtasks):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,318 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	todo "github.com/ZeroBl21/cli/ch02"
+	"github.com/ZeroBl21/cli/ch02/repository"
+	"github.com/ZeroBl21/cli/ch02/server"
+)
+
+func setupAPI(t *testing.T) (string, *todo.FileStore, *todo.History) {
+	t.Helper()
+
+	fname := filepath.Join(t.TempDir(), "todo.json")
+	store := todo.NewFileStore(fname)
+
+	ts := httptest.NewServer(server.NewHandler(func(list string) (todo.Repository, *todo.History, error) {
+		return todo.NewNamedFileStore(fname, list), todo.NewHistory(fname + "." + list + ".history"), nil
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts.URL, store, todo.NewHistory(fname + "." + todo.DefaultList + ".history")
+}
+
+func TestAPI(t *testing.T) {
+	url, store, _ := setupAPI(t)
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		code     int
+		expected string
+	}{
+		{"AddTask1", http.MethodPost, "/todo", `{"task":"Task 1"}`, http.StatusCreated, ""},
+		{"AddTask2", http.MethodPost, "/todo", `{"task":"Task 2","priority":"high","tags":["work"]}`, http.StatusCreated, ""},
+		{"AddTask3", http.MethodPost, "/todo", `{"task":"Task 3","due":"2024-05-01"}`, http.StatusCreated, ""},
+		{"AddBlank", http.MethodPost, "/todo", `{"task":""}`, http.StatusBadRequest, ""},
+		{"AddBadPriority", http.MethodPost, "/todo", `{"task":"x","priority":"urgent"}`, http.StatusBadRequest, ""},
+		{"AddInvalidJSON", http.MethodPost, "/todo", `{"task":`, http.StatusBadRequest, ""},
+		{"Complete", http.MethodPatch, "/todo/1", `{"done":true}`, http.StatusOK, ""},
+		{"Reopen", http.MethodPatch, "/todo/1", `{"done":false}`, http.StatusOK, ""},
+		{"GetReopened", http.MethodGet, "/todo?pending=true", "", http.StatusOK, "[ ] 1: Task 1\n[ ] 2: Task 2 (high) #work\n[ ] 3: Task 3 due:2024-05-01\n"},
+		{"CompleteAgain", http.MethodPatch, "/todo/1", `{"done":true}`, http.StatusOK, ""},
+		{"PatchEmpty", http.MethodPatch, "/todo/1", `{}`, http.StatusBadRequest, ""},
+		{"Edit", http.MethodPatch, "/todo/2", `{"task":"Task 2 edited"}`, http.StatusOK, ""},
+		{"EditBlank", http.MethodPatch, "/todo/2", `{"task":""}`, http.StatusBadRequest, ""},
+		{"CompleteNotFound", http.MethodPatch, "/todo/9", `{"done":true}`, http.StatusNotFound, ""},
+		{"CompleteInvalidID", http.MethodPatch, "/todo/one", `{"done":true}`, http.StatusBadRequest, ""},
+		{"Delete", http.MethodDelete, "/todo/3", "", http.StatusNoContent, ""},
+		{"DeleteNotFound", http.MethodDelete, "/todo/3", "", http.StatusNotFound, ""},
+		{"MethodNotAllowed", http.MethodPost, "/todo/1", "", http.StatusMethodNotAllowed, ""},
+		{"GetPending", http.MethodGet, "/todo?pending=true", "", http.StatusOK, "[ ] 2: Task 2 edited (high) #work\n"},
+		{"GetAll", http.MethodGet, "/todo", "", http.StatusOK, "[X] 1: Task 1\n[ ] 2: Task 2 edited (high) #work\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, url+tc.path, bytes.NewBufferString(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.code {
+				body, _ := io.ReadAll(resp.Body)
+				t.Fatalf("Expected status %d, got %q instead: %s", tc.code, resp.Status, body)
+			}
+
+			if tc.expected == "" {
+				return
+			}
+
+			l := todo.List{}
+			if err := json.NewDecoder(resp.Body).Decode(&l); err != nil {
+				t.Fatal(err)
+			}
+
+			if res := l.String(); res != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, res)
+			}
+		})
+	}
+
+	l := todo.List{}
+	if err := store.Get(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 2 {
+		t.Errorf("Expected %d tasks stored, got %d instead", 2, len(l))
+	}
+}
+
+func TestAPIListsSubtasks(t *testing.T) {
+	url, store, _ := setupAPI(t)
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		code     int
+		expected string
+	}{
+		{"AddDefault", http.MethodPost, "/todo", `{"task":"Task 1"}`, http.StatusCreated, ""},
+		{"AddWork", http.MethodPost, "/todo?list=work", `{"task":"Report"}`, http.StatusCreated, ""},
+		{"AddSubtask", http.MethodPost, "/todo?list=work", `{"task":"Draft","parent":1}`, http.StatusCreated, ""},
+		{"AddNested", http.MethodPost, "/todo?list=work", `{"task":"Typo","parent":2}`, http.StatusBadRequest, ""},
+		{"AddMissingParent", http.MethodPost, "/todo?list=work", `{"task":"Typo","parent":9}`, http.StatusNotFound, ""},
+		{"CompletePending", http.MethodPatch, "/todo/1?list=work", `{"done":true}`, http.StatusConflict, ""},
+		{"CompleteCascade", http.MethodPatch, "/todo/1?list=work&cascade=true", `{"done":true}`, http.StatusOK, ""},
+		{"GetWork", http.MethodGet, "/todo?list=work", "", http.StatusOK, "[X] 1: Report\n  [X] 2: Draft\n"},
+		{"DeleteParent", http.MethodDelete, "/todo/1?list=work", "", http.StatusNoContent, ""},
+		{"GetWorkEmpty", http.MethodGet, "/todo?list=work", "", http.StatusOK, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, url+tc.path, bytes.NewBufferString(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.code {
+				body, _ := io.ReadAll(resp.Body)
+				t.Fatalf("Expected status %d, got %q instead: %s", tc.code, resp.Status, body)
+			}
+
+			if tc.method != http.MethodGet {
+				return
+			}
+
+			l := todo.List{}
+			if err := json.NewDecoder(resp.Body).Decode(&l); err != nil {
+				t.Fatal(err)
+			}
+
+			if res := l.String(); res != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, res)
+			}
+		})
+	}
+
+	l := todo.List{}
+	if err := store.Get(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "[ ] 1: Task 1\n"; l.String() != expected {
+		t.Errorf("Expected default list %q, got %q instead", expected, l.String())
+	}
+}
+
+func TestAPIHistory(t *testing.T) {
+	url, store, hist := setupAPI(t)
+
+	err := hist.Update(store, &todo.List{}, todo.OpAdd, func(l *todo.List) (int, error) {
+		l.Add("Task 1")
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPost, "/todo", `{"task":"Task 2"}`},
+		{http.MethodPatch, "/todo/2", `{"done":true}`},
+		{http.MethodPatch, "/todo/2", `{"task":"Task 2 edited"}`},
+		{http.MethodDelete, "/todo/1", ""},
+	} {
+		r, err := http.NewRequest(req.method, url+req.path, bytes.NewBufferString(req.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	entries, err := hist.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := []todo.Op{}
+	for _, c := range entries {
+		ops = append(ops, c.Op)
+	}
+
+	expOps := []todo.Op{todo.OpAdd, todo.OpAdd, todo.OpComplete, todo.OpEdit, todo.OpDelete}
+	if !slices.Equal(ops, expOps) {
+		t.Errorf("Expected history %v, got %v instead", expOps, ops)
+	}
+
+	var change todo.Change
+	l := todo.List{}
+	err = todo.Update(store, &l, func(l *todo.List) error {
+		change, err = hist.Undo(l)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hist.Record(change); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "[ ] 1: Task 1\n[X] 2: Task 2 edited\n"; l.String() != expected {
+		t.Errorf("Expected undo to restore the deleted task, got %q instead", l.String())
+	}
+}
+
+func TestAPIUndoAfterPut(t *testing.T) {
+	url, store, hist := setupAPI(t)
+
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPost, "/todo", `{"task":"Task 1"}`},
+		{http.MethodPatch, "/todo/1", `{"task":"Task 1 edited"}`},
+		{http.MethodPut, "/todo", `[{"ID":1,"Task":"Task 1 replaced"}]`},
+	} {
+		r, err := http.NewRequest(req.method, url+req.path, bytes.NewBufferString(req.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	l := todo.List{}
+	err := todo.Update(store, &l, func(l *todo.List) error {
+		_, err := hist.Undo(l)
+		return err
+	})
+	if !errors.Is(err, todo.ErrItemChanged) {
+		t.Errorf("Expected error %q, got %v instead", todo.ErrItemChanged, err)
+	}
+
+	if err := store.Get(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "[ ] 1: Task 1 replaced\n"; l.String() != expected {
+		t.Errorf("Expected %q, got %q instead", expected, l.String())
+	}
+}
+
+func TestHTTPRepository(t *testing.T) {
+	url, store, _ := setupAPI(t)
+
+	r1 := repository.NewHTTPRepo(url + "/todo")
+	r2 := repository.NewHTTPRepo(url + "/todo")
+
+	l1, l2 := todo.List{}, todo.List{}
+
+	if err := r1.Get(&l1); err != nil {
+		t.Fatal(err)
+	}
+	if err := r2.Get(&l2); err != nil {
+		t.Fatal(err)
+	}
+
+	l1.Add("Task 1")
+	if err := r1.Save(&l1); err != nil {
+		t.Fatal(err)
+	}
+
+	l2.Add("Task 2")
+	if err := r2.Save(&l2); !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("Expected error %q, got %q instead", todo.ErrConflict, err)
+	}
+
+	err := todo.Update(r2, &l2, func(l *todo.List) error {
+		l.Add("Task 2")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := todo.List{}
+	if err := store.Get(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[ ] 1: Task 1\n[ ] 2: Task 2\n"
+	if res := l.String(); res != expected {
+		t.Errorf("Expected %q, got %q instead", expected, res)
+	}
+}
@@ -4,20 +4,148 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 )
 
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+var (
+	ErrInvalidPriority = errors.New("Invalid priority")
+	ErrNotExists       = errors.New("Item does not exist")
+	ErrBlankTask       = errors.New("Task cannot be blank")
+	ErrPendingSubtasks = errors.New("Item has pending subtasks")
+	ErrNestedSubtask   = errors.New("Subtasks cannot have subtasks")
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityMedium:
+		return "medium"
+	case PriorityHigh:
+		return "high"
+	}
+
+	return "none"
+}
+
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return PriorityNone, nil
+	case "l", "low":
+		return PriorityLow, nil
+	case "m", "med", "medium":
+		return PriorityMedium, nil
+	case "h", "high":
+		return PriorityHigh, nil
+	}
+
+	return PriorityNone, fmt.Errorf("%w: %q", ErrInvalidPriority, s)
+}
+
 type item struct {
+	ID          int
 	Task        string
 	Done        bool
 	CreatedAt   time.Time
 	CompletedAt time.Time
+	Priority    Priority
+	Due         time.Time
+	Tags        []string
+	Repeat      string
+	Parent      int
+
+	// MaxID is the highest ID the list had handed out when items were last
+	// removed from it, so their IDs are not handed out again.
+	MaxID int `json:",omitempty"`
+}
+
+func (i item) hasTag(tag string) bool {
+	for _, t := range i.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i item) details() string {
+	details := ""
+
+	if i.Priority != PriorityNone {
+		details += fmt.Sprintf(" (%s)", i.Priority)
+	}
+
+	if !i.Due.IsZero() {
+		layout := time.DateOnly
+		if h, m, _ := i.Due.Clock(); h != 0 || m != 0 {
+			layout = "2006-01-02T15:04"
+		}
+
+		details += fmt.Sprintf(" due:%s", i.Due.Format(layout))
+	}
+
+	if i.Repeat != "" {
+		details += fmt.Sprintf(" repeat:%s", i.Repeat)
+	}
+
+	for _, t := range i.Tags {
+		details += " #" + t
+	}
+
+	return details
 }
 
 type List []item
 
-func (l *List) Add(task string) {
+// ItemOption sets an optional attribute of a task created by Add.
+type ItemOption func(*item)
+
+func WithPriority(p Priority) ItemOption {
+	return func(i *item) {
+		i.Priority = p
+	}
+}
+
+func WithDue(due time.Time) ItemOption {
+	return func(i *item) {
+		i.Due = due
+	}
+}
+
+func WithTags(tags ...string) ItemOption {
+	return func(i *item) {
+		for _, t := range tags {
+			t = strings.TrimSpace(t)
+			if t != "" && !i.hasTag(t) {
+				i.Tags = append(i.Tags, t)
+			}
+		}
+	}
+}
+
+// WithRecurrence makes the task repeat: completing it adds the next
+// occurrence with a new due date.
+func WithRecurrence(r Recurrence) ItemOption {
+	return func(i *item) {
+		i.Repeat = r.String()
+	}
+}
+
+func (l *List) Add(task string, opts ...ItemOption) {
 	t := item{
 		Task:        task,
 		Done:        false,
@@ -25,89 +153,409 @@ func (l *List) Add(task string) {
 		CompletedAt: time.Time{},
 	}
 
+	for _, opt := range opts {
+		opt(&t)
+	}
+
+	t.ID = l.nextID()
+
 	*l = append(*l, t)
 }
 
-func (l *List) Complete(idx int) error {
-	ls := *l
+// AddSubtask adds a task under the top level item with the given ID.
+func (l *List) AddSubtask(parent int, task string, opts ...ItemOption) error {
+	idx, err := l.Find(parent)
+	if err != nil {
+		return err
+	}
 
-	if idx <= 0 || idx > len(ls) {
-		return fmt.Errorf("Item %d does not exists", idx)
+	if (*l)[idx].Parent != 0 {
+		return fmt.Errorf("%w: %d", ErrNestedSubtask, parent)
 	}
 
-	ls[idx-1].Done = true
-	ls[idx-1].CompletedAt = time.Now()
+	l.Add(task, opts...)
+	(*l)[len(*l)-1].Parent = parent
 
 	return nil
 }
 
-func (l *List) Delete(idx int) error {
+func (l *List) subtasks(parent int) []int {
+	ids := []int{}
+
+	for _, t := range *l {
+		if t.Parent == parent && parent != 0 {
+			ids = append(ids, t.ID)
+		}
+	}
+
+	return ids
+}
+
+func (l *List) nextID() int {
+	id := 0
+
+	for _, t := range *l {
+		id = max(id, t.ID, t.MaxID)
+	}
+
+	return id + 1
+}
+
+// remove deletes the items del reports and keeps the highest ID handed out
+// so far on the items left.
+func (l *List) remove(del func(item) bool) {
+	last := l.nextID() - 1
+
+	*l = slices.DeleteFunc(*l, del)
+
+	for idx := range *l {
+		(*l)[idx].MaxID = last
+	}
+}
+
+// Find returns the index in the list of the item with the given ID.
+func (l *List) Find(id int) (int, error) {
+	for idx, t := range *l {
+		if t.ID == id {
+			return idx, nil
+		}
+	}
+
+	return -1, fmt.Errorf("%w: %d", ErrNotExists, id)
+}
+
+// Complete marks the item as done. Items with pending subtasks cannot be
+// completed, see CompleteCascade.
+func (l *List) Complete(id int) error {
+	idx, err := l.Find(id)
+	if err != nil {
+		return err
+	}
+
 	ls := *l
-	if idx <= 0 || idx > len(ls) {
-		return fmt.Errorf("Item %d does not exists", idx)
+	if ls[idx].Done {
+		return nil
+	}
+
+	for _, sub := range l.subtasks(id) {
+		if i, _ := l.Find(sub); !ls[i].Done {
+			return fmt.Errorf("%w: %d", ErrPendingSubtasks, id)
+		}
 	}
 
-	*l = append(ls[:idx-1], ls[idx:]...)
+	ls[idx].Done = true
+	ls[idx].CompletedAt = time.Now()
+
+	if ls[idx].Repeat != "" {
+		return l.spawnNext(ls[idx])
+	}
 
 	return nil
 }
 
-func (l *List) Save(filename string) error {
-	js, err := json.Marshal(l)
+// Reopen marks a completed item as pending again.
+func (l *List) Reopen(id int) error {
+	idx, err := l.Find(id)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filename, js, 0644)
+	(*l)[idx].Done = false
+	(*l)[idx].CompletedAt = time.Time{}
+
+	return nil
 }
 
-func (l *List) Get(filename string) error {
-	file, err := os.ReadFile(filename)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil
+// CompleteCascade completes the item along with its pending subtasks.
+func (l *List) CompleteCascade(id int) error {
+	if _, err := l.Find(id); err != nil {
+		return err
+	}
+
+	for _, sub := range l.subtasks(id) {
+		if err := l.Complete(sub); err != nil {
+			return err
 		}
+	}
 
+	return l.Complete(id)
+}
+
+// spawnNext adds the occurrence following the completed recurring task t.
+func (l *List) spawnNext(t item) error {
+	r, err := ParseRecurrence(t.Repeat)
+	if err != nil {
 		return err
 	}
 
-	if len(file) == 0 {
+	// Keeps monthly tasks on the day they were first due, so one due on the
+	// 31st comes back on the 31st after a shorter month.
+	if r.Every == RepeatMonthly && r.Day == 0 && !r.FromCompletion && !t.Due.IsZero() {
+		r.Day = t.Due.Day()
+	}
+
+	next := r.nextDue(t.Due, t.CompletedAt)
+	if next.IsZero() {
 		return nil
 	}
 
-	return json.Unmarshal(file, l)
+	l.Add(t.Task,
+		WithPriority(t.Priority),
+		WithDue(next),
+		WithTags(t.Tags...),
+		WithRecurrence(r),
+	)
+	(*l)[len(*l)-1].Parent = t.Parent
+
+	return nil
 }
 
-func (l *List) String() string {
+// Delete removes the item and its subtasks.
+func (l *List) Delete(id int) error {
+	if _, err := l.Find(id); err != nil {
+		return err
+	}
+
+	l.remove(func(t item) bool {
+		return t.ID == id || (t.Parent == id && id != 0)
+	})
+
+	return nil
+}
+
+func (l *List) Edit(id int, task string) error {
+	if strings.TrimSpace(task) == "" {
+		return ErrBlankTask
+	}
+
+	idx, err := l.Find(id)
+	if err != nil {
+		return err
+	}
+
+	(*l)[idx].Task = task
+
+	return nil
+}
+
+// UnmarshalJSON assigns IDs to items saved before they had one, so lists
+// written by older versions keep their positions as IDs.
+func (l *List) UnmarshalJSON(data []byte) error {
+	items := []item{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	*l = items
+	l.AssignIDs()
+
+	return nil
+}
+
+// AssignIDs gives every item without an ID the next free one.
+func (l *List) AssignIDs() {
+	ls := *l
+	next := l.nextID()
+
+	for idx := range ls {
+		if ls[idx].ID == 0 {
+			ls[idx].ID = next
+			next++
+		}
+	}
+}
+
+// Save writes the list to filename.
+//
+// Deprecated: Save cannot tell whether the file changed since the list was
+// read. Use a FileStore, or Update, which return ErrConflict instead.
+func (l *List) Save(filename string) error {
+	return NewFileStore(filename).Save(l)
+}
+
+// Get reads the list from filename.
+//
+// Deprecated: use a FileStore, whose Save detects conflicting writes.
+func (l *List) Get(filename string) error {
+	return NewFileStore(filename).Get(l)
+}
+
+// Filter reports whether a task should be kept by Filter and Format.
+type Filter func(item) bool
+
+func HasPriority(p Priority) Filter {
+	return func(i item) bool {
+		return i.Priority == p
+	}
+}
+
+func HasTag(tag string) Filter {
+	return func(i item) bool {
+		return i.hasTag(tag)
+	}
+}
+
+// DueBefore keeps tasks with a due date on or before t.
+func DueBefore(t time.Time) Filter {
+	return func(i item) bool {
+		return !i.Due.IsZero() && !i.Due.After(t)
+	}
+}
+
+// Overdue keeps pending tasks whose due date is before the day of now.
+func Overdue(now time.Time) Filter {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	return func(i item) bool {
+		return !i.Done && !i.Due.IsZero() && i.Due.Before(today)
+	}
+}
+
+// IsPending keeps tasks that have not been completed yet.
+func IsPending(i item) bool {
+	return i.CompletedAt.IsZero()
+}
+
+func (l *List) Filter(filters ...Filter) List {
+	filtered := List{}
+
+next:
+	for _, task := range *l {
+		for _, keep := range filters {
+			if !keep(task) {
+				continue next
+			}
+		}
+
+		filtered = append(filtered, task)
+	}
+
+	return filtered
+}
+
+type SortKey int
+
+const (
+	SortNone SortKey = iota
+	SortCreated
+	SortPriority
+	SortDue
+)
+
+var ErrInvalidSortKey = errors.New("Invalid sort key")
+
+func ParseSortKey(s string) (SortKey, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return SortNone, nil
+	case "created":
+		return SortCreated, nil
+	case "priority":
+		return SortPriority, nil
+	case "due":
+		return SortDue, nil
+	}
+
+	return SortNone, fmt.Errorf("%w: %q", ErrInvalidSortKey, s)
+}
+
+// less orders tasks by the given key, falling back to the creation time.
+// Tasks without a due date sort after the ones that have it.
+func (by SortKey) less(a, b item) bool {
+	switch by {
+	case SortPriority:
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+	case SortDue:
+		if !a.Due.Equal(b.Due) {
+			if a.Due.IsZero() || b.Due.IsZero() {
+				return b.Due.IsZero()
+			}
+
+			return a.Due.Before(b.Due)
+		}
+	case SortNone:
+		return false
+	}
+
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+func (l *List) Sort(by SortKey) {
+	ls := *l
+
+	sort.SliceStable(ls, func(i, j int) bool {
+		return by.less(ls[i], ls[j])
+	})
+}
+
+// Format renders the items kept by filters in the given order, with each
+// subtask indented under its parent.
+func (l *List) Format(by SortKey, filters ...Filter) string {
+	view := l.Filter(filters...)
+	view.Sort(by)
+
 	formatted := ""
 
-	for idx, task := range *l {
-		prefix := "[ ] "
-		if task.Done {
-			prefix = "[X] "
+	for _, task := range view.Tree() {
+		indent := ""
+		if view.isSubtask(task) {
+			indent = "  "
 		}
 
-		formatted += fmt.Sprintf("%s%d: %s\n", prefix, idx+1, task.Task)
+		formatted += task.format(indent)
 	}
 
 	return formatted
 }
 
-func (l *List) Pending() string {
-	formatted := ""
+// Tree orders the items so that each subtask follows its parent, the order
+// Format renders them in. Subtasks whose parent is not in the list stay in
+// place.
+func (l List) Tree() List {
+	ordered := make(List, 0, len(l))
 
-	for idx, task := range *l {
-		if !task.CompletedAt.IsZero() {
+	for _, t := range l {
+		if l.isSubtask(t) {
 			continue
 		}
 
-		prefix := "[ ] "
-		if task.Done {
-			prefix = "[X] "
+		ordered = append(ordered, t)
+
+		for _, sub := range l {
+			if sub.Parent == t.ID && t.ID != 0 {
+				ordered = append(ordered, sub)
+			}
 		}
+	}
+
+	return ordered
+}
 
-		formatted += fmt.Sprintf("%s%d: %s\n", prefix, idx+1, task.Task)
+func (l List) isSubtask(t item) bool {
+	if t.Parent == 0 {
+		return false
 	}
 
-	return formatted
+	_, err := l.Find(t.Parent)
+	return err == nil
+}
+
+func (i item) format(indent string) string {
+	prefix := "[ ] "
+	if i.Done {
+		prefix = "[X] "
+	}
+
+	return fmt.Sprintf("%s%s%d: %s%s\n", indent, prefix, i.ID, i.Task, i.details())
+}
+
+func (l *List) String() string {
+	return l.Format(SortNone)
+}
+
+func (l *List) Pending() string {
+	return l.Format(SortNone, IsPending)
 }
@@ -1,8 +1,16 @@
 package todo_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	todo "github.com/ZeroBl21/cli/ch02"
 )
@@ -67,6 +75,56 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestStableIDs(t *testing.T) {
+	l := todo.List{}
+
+	for _, v := range []string{"Task 1", "Task 2", "Task 3", "Task 4"} {
+		l.Add(v)
+	}
+
+	if err := l.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Complete(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if !l[1].Done || l[1].Task != "Task 3" {
+		t.Errorf("Expected %q to be completed, got %+v instead.", "Task 3", l[1])
+	}
+
+	if err := l.Delete(2); !errors.Is(err, todo.ErrNotExists) {
+		t.Errorf("Expected error %q, got %q instead.", todo.ErrNotExists, err)
+	}
+
+	l.Add("Task 5")
+	if l[3].ID != 5 {
+		t.Errorf("Expected ID %d, got %d instead.", 5, l[3].ID)
+	}
+}
+
+func TestDeletedIDsNotReused(t *testing.T) {
+	l := todo.List{}
+
+	for _, v := range []string{"Task 1", "Task 2", "Task 3"} {
+		l.Add(v)
+	}
+
+	if err := l.Delete(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Add("Task 4")
+	if l[1].ID != 4 {
+		t.Errorf("Expected ID %d, got %d instead.", 4, l[1].ID)
+	}
+}
+
 func TestSaveGet(t *testing.T) {
 	l1 := todo.List{}
 	l2 := todo.List{}
@@ -96,3 +154,678 @@ func TestSaveGet(t *testing.T) {
 		t.Errorf("Task %q should match %q task.", l1[0].Task, l2[0].Task)
 	}
 }
+
+func TestAddWithOptions(t *testing.T) {
+	l := todo.List{}
+
+	due := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.Local)
+	l.Add("New Task",
+		todo.WithPriority(todo.PriorityHigh),
+		todo.WithDue(due),
+		todo.WithTags("work", "release", "work"),
+	)
+
+	if l[0].Priority != todo.PriorityHigh {
+		t.Errorf("Expected priority %q, got %q instead.", todo.PriorityHigh, l[0].Priority)
+	}
+
+	if !l[0].Due.Equal(due) {
+		t.Errorf("Expected due date %v, got %v instead.", due, l[0].Due)
+	}
+
+	if len(l[0].Tags) != 2 {
+		t.Errorf("Expected %d tags, got %v instead.", 2, l[0].Tags)
+	}
+}
+
+func TestFilterSort(t *testing.T) {
+	l := todo.List{}
+	now := time.Date(2024, time.May, 10, 12, 0, 0, 0, time.Local)
+
+	l.Add("Someday", todo.WithPriority(todo.PriorityLow))
+	l.Add("Late", todo.WithDue(now.AddDate(0, 0, -2)), todo.WithTags("work"))
+	l.Add("Urgent", todo.WithPriority(todo.PriorityHigh), todo.WithTags("work"))
+	l.Add("Today", todo.WithDue(now), todo.WithPriority(todo.PriorityHigh))
+
+	testCases := []struct {
+		name     string
+		filters  []todo.Filter
+		expected []string
+	}{
+		{"Priority", []todo.Filter{todo.HasPriority(todo.PriorityHigh)}, []string{"Urgent", "Today"}},
+		{"Tag", []todo.Filter{todo.HasTag("WORK")}, []string{"Late", "Urgent"}},
+		{"DueBefore", []todo.Filter{todo.DueBefore(now)}, []string{"Late", "Today"}},
+		{"Overdue", []todo.Filter{todo.Overdue(now)}, []string{"Late"}},
+		{"Combined", []todo.Filter{todo.HasTag("work"), todo.HasPriority(todo.PriorityHigh)}, []string{"Urgent"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := l.Filter(tc.filters...)
+
+			if len(res) != len(tc.expected) {
+				t.Fatalf("Expected %d tasks, got %d instead.", len(tc.expected), len(res))
+			}
+
+			for i, task := range tc.expected {
+				if res[i].Task != task {
+					t.Errorf("Expected %q, got %q instead.", task, res[i].Task)
+				}
+			}
+		})
+	}
+
+	l.Sort(todo.SortDue)
+
+	expected := []string{"Late", "Today", "Someday", "Urgent"}
+	for i, task := range expected {
+		if l[i].Task != task {
+			t.Errorf("Expected %q at %d, got %q instead.", task, i, l[i].Task)
+		}
+	}
+}
+
+func TestFormatShowsIDs(t *testing.T) {
+	l := todo.List{}
+
+	l.Add("Task 1", todo.WithPriority(todo.PriorityLow))
+	l.Add("Task 2")
+	l.Add("Task 3", todo.WithPriority(todo.PriorityHigh), todo.WithTags("work"))
+
+	expected := "[ ] 3: Task 3 (high) #work\n[ ] 1: Task 1 (low)\n"
+	res := l.Format(todo.SortPriority, todo.HasTag("work"))
+	res += l.Format(todo.SortPriority, todo.HasPriority(todo.PriorityLow))
+
+	if expected != res {
+		t.Errorf("Expected %q, got %q instead.", expected, res)
+	}
+}
+
+func TestSubtasks(t *testing.T) {
+	l := todo.List{}
+
+	l.Add("Release")
+	l.Add("Groceries")
+	if err := l.AddSubtask(1, "Tag version"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddSubtask(1, "Publish notes"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.AddSubtask(3, "Nested"); !errors.Is(err, todo.ErrNestedSubtask) {
+		t.Errorf("Expected error %q, got %v instead.", todo.ErrNestedSubtask, err)
+	}
+
+	if err := l.AddSubtask(9, "Orphan"); !errors.Is(err, todo.ErrNotExists) {
+		t.Errorf("Expected error %q, got %v instead.", todo.ErrNotExists, err)
+	}
+
+	expected := "[ ] 1: Release\n  [ ] 3: Tag version\n  [ ] 4: Publish notes\n[ ] 2: Groceries\n"
+	if res := l.String(); res != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, res)
+	}
+
+	if err := l.Complete(1); !errors.Is(err, todo.ErrPendingSubtasks) {
+		t.Errorf("Expected error %q, got %v instead.", todo.ErrPendingSubtasks, err)
+	}
+
+	if err := l.Complete(3); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = "[ ] 1: Release\n  [ ] 4: Publish notes\n[ ] 2: Groceries\n"
+	if res := l.Pending(); res != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, res)
+	}
+
+	if err := l.CompleteCascade(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := l.Pending(); res != "[ ] 2: Groceries\n" {
+		t.Errorf("Expected only %q pending, got %q instead.", "Groceries", res)
+	}
+
+	if err := l.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := l.String(); res != "[ ] 2: Groceries\n" {
+		t.Errorf("Expected subtasks to be deleted, got %q instead.", res)
+	}
+}
+
+func TestUndoDeleteSubtasks(t *testing.T) {
+	h := todo.NewHistory(filepath.Join(t.TempDir(), "todo.json.history"))
+	l := todo.List{}
+
+	l.Add("Release")
+	l.Add("Groceries")
+	if err := l.AddSubtask(1, "Tag version"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := l.String()
+	before := slices.Clone(l)
+
+	if err := l.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Record(todo.NewChange(todo.OpDelete, 1, before, l)); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := h.Undo(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res := l.String(); res != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, res)
+	}
+
+	if err := h.Record(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.Redo(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := l.String(); res != "[ ] 2: Groceries\n" {
+		t.Errorf("Expected %q, got %q instead.", "[ ] 2: Groceries\n", res)
+	}
+}
+
+func TestNamedLists(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "todo.json")
+
+	l := todo.List{}
+	l.Add("Default task")
+	if err := l.Save(fname); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("[")) {
+		t.Errorf("Expected a single list to be stored as an array, got %s", data)
+	}
+
+	work := todo.List{}
+	work.Add("Work task")
+	if err := todo.NewNamedFileStore(fname, "work").Save(&work); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := todo.NewFileStore(fname).Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(names, []string{todo.DefaultList, "work"}) {
+		t.Errorf("Expected lists %v, got %v instead.", []string{todo.DefaultList, "work"}, names)
+	}
+
+	for name, expected := range map[string]string{
+		todo.DefaultList: "[ ] 1: Default task\n",
+		"work":           "[ ] 1: Work task\n",
+		"home":           "",
+	} {
+		l := todo.List{}
+		if err := todo.NewNamedFileStore(fname, name).Get(&l); err != nil {
+			t.Fatal(err)
+		}
+
+		if l.String() != expected {
+			t.Errorf("Expected list %q to be %q, got %q instead.", name, expected, l.String())
+		}
+	}
+}
+
+func TestGetLegacyFile(t *testing.T) {
+	l := todo.List{}
+
+	tf, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatalf("Error creating temp file %s", err)
+	}
+	defer os.Remove(tf.Name())
+
+	legacy := `[{"Task":"Old Task","Done":false,"CreatedAt":"2024-01-01T10:00:00Z","CompletedAt":"0001-01-01T00:00:00Z"}]`
+	if _, err := tf.WriteString(legacy); err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+
+	if err := l.Get(tf.Name()); err != nil {
+		t.Fatalf("Error getting list from file: %s", err)
+	}
+
+	if l[0].ID != 1 || l[0].Task != "Old Task" || l[0].Priority != todo.PriorityNone || !l[0].Due.IsZero() {
+		t.Errorf("Unexpected legacy task %+v", l[0])
+	}
+}
+
+func TestSaveConflict(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "todo.json")
+
+	s1 := todo.NewFileStore(fname)
+	s2 := todo.NewFileStore(fname)
+
+	l1 := todo.List{}
+	l2 := todo.List{}
+
+	if err := s1.Get(&l1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.Get(&l2); err != nil {
+		t.Fatal(err)
+	}
+
+	l1.Add("Task 1")
+	if err := s1.Save(&l1); err != nil {
+		t.Fatalf("Error saving list to file: %s", err)
+	}
+
+	l2.Add("Task 2")
+	if err := s2.Save(&l2); !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("Expected error %q, got %q instead.", todo.ErrConflict, err)
+	}
+
+	if err := s2.Get(&l2); err != nil {
+		t.Fatal(err)
+	}
+
+	l2.Add("Task 2")
+	if err := s2.Save(&l2); err != nil {
+		t.Fatalf("Error saving reloaded list: %s", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp") {
+			t.Errorf("Temporary file %q left behind", e.Name())
+		}
+	}
+}
+
+func TestConcurrentUpdate(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "todo.json")
+
+	const n = 10
+	errCh := make(chan error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			l := todo.List{}
+			errCh <- todo.Update(todo.NewFileStore(fname), &l, func(l *todo.List) error {
+				l.Add(fmt.Sprintf("Task %d", i))
+				return nil
+			})
+		}(i)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	saved := 0
+	for err := range errCh {
+		if err != nil && !errors.Is(err, todo.ErrConflict) {
+			t.Fatal(err)
+		}
+
+		if err == nil {
+			saved++
+		}
+	}
+
+	l := todo.List{}
+	if err := l.Get(fname); err != nil {
+		t.Fatal(err)
+	}
+
+	if saved == 0 || len(l) != saved {
+		t.Errorf("Expected %d saved tasks, got %d instead.", saved, len(l))
+	}
+}
+
+func TestEdit(t *testing.T) {
+	l := todo.List{}
+	l.Add("New Task")
+
+	if err := l.Edit(1, "Edited Task"); err != nil {
+		t.Fatal(err)
+	}
+
+	if l[0].Task != "Edited Task" {
+		t.Errorf("Expected %q, got %q instead.", "Edited Task", l[0].Task)
+	}
+
+	if err := l.Edit(1, " "); !errors.Is(err, todo.ErrBlankTask) {
+		t.Errorf("Expected error %q, got %q instead.", todo.ErrBlankTask, err)
+	}
+
+	if err := l.Edit(2, "Missing"); !errors.Is(err, todo.ErrNotExists) {
+		t.Errorf("Expected error %q, got %q instead.", todo.ErrNotExists, err)
+	}
+}
+
+func TestHistoryUndoRedo(t *testing.T) {
+	h := todo.NewHistory(filepath.Join(t.TempDir(), "todo.json.history"))
+	l := todo.List{}
+
+	record := func(op todo.Op, id int, fn func() error) {
+		t.Helper()
+
+		before := slices.Clone(l)
+		if err := fn(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := h.Record(todo.NewChange(op, id, before, l)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revert := func(fn func(*todo.List) (todo.Change, error)) {
+		t.Helper()
+
+		c, err := fn(&l)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := h.Record(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	record(todo.OpAdd, 1, func() error { l.Add("Task 1"); return nil })
+	record(todo.OpAdd, 2, func() error { l.Add("Task 2"); return nil })
+	record(todo.OpEdit, 1, func() error { return l.Edit(1, "Task 1 edited") })
+	record(todo.OpDelete, 1, func() error { return l.Delete(1) })
+
+	steps := []struct {
+		fn       func(*todo.List) (todo.Change, error)
+		expected string
+	}{
+		{h.Undo, "[ ] 1: Task 1 edited\n[ ] 2: Task 2\n"},
+		{h.Undo, "[ ] 1: Task 1\n[ ] 2: Task 2\n"},
+		{h.Redo, "[ ] 1: Task 1 edited\n[ ] 2: Task 2\n"},
+		{h.Undo, "[ ] 1: Task 1\n[ ] 2: Task 2\n"},
+		{h.Undo, "[ ] 1: Task 1\n"},
+		{h.Undo, ""},
+	}
+
+	for i, s := range steps {
+		revert(s.fn)
+
+		if res := l.String(); res != s.expected {
+			t.Errorf("Step %d: expected %q, got %q instead.", i, s.expected, res)
+		}
+	}
+
+	if _, err := h.Undo(&l); !errors.Is(err, todo.ErrNothingToUndo) {
+		t.Errorf("Expected error %q, got %q instead.", todo.ErrNothingToUndo, err)
+	}
+
+	record(todo.OpAdd, 1, func() error { l.Add("Task 3"); return nil })
+
+	if _, err := h.Redo(&l); !errors.Is(err, todo.ErrNothingToRedo) {
+		t.Errorf("Expected error %q, got %q instead.", todo.ErrNothingToRedo, err)
+	}
+
+	entries, err := h.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 11 || entries[10].Seq != 11 {
+		t.Errorf("Expected %d history entries, got %d instead.", 11, len(entries))
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	// Friday, 10:30
+	from := time.Date(2024, time.January, 26, 10, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		rule     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"daily", from, time.Date(2024, time.January, 27, 0, 0, 0, 0, time.UTC)},
+		{"weekly", from, time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"weekly:mon,wed", from, time.Date(2024, time.January, 29, 0, 0, 0, 0, time.UTC)},
+		{"Weekly:Friday", from, time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"monthly", from, time.Date(2024, time.February, 26, 0, 0, 0, 0, time.UTC)},
+		{"monthly", time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"monthly:31", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"monthly:31", from, time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"cron:0 9 * * 1-5", from, time.Date(2024, time.January, 29, 9, 0, 0, 0, time.UTC)},
+		{"cron:*/15 * * * *", from, time.Date(2024, time.January, 26, 10, 45, 0, 0, time.UTC)},
+		{"cron:0 0 1 */3 *", from, time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"cron:30 8 13 * 5", from, time.Date(2024, time.February, 2, 8, 30, 0, 0, time.UTC)},
+		{"after:daily", from, time.Date(2024, time.January, 27, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.rule, func(t *testing.T) {
+			r, err := todo.ParseRecurrence(tc.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res := r.Next(tc.from); !res.Equal(tc.expected) {
+				t.Errorf("Expected %v, got %v instead.", tc.expected, res)
+			}
+
+			again, err := todo.ParseRecurrence(r.String())
+			if err != nil || again.String() != r.String() {
+				t.Errorf("Rule %q does not round trip: %q, %v", tc.rule, again, err)
+			}
+		})
+	}
+
+	for _, rule := range []string{"", "hourly", "daily:2", "weekly:funday", "monthly:32", "monthly:last", "cron:* * *", "cron:60 * * * *", "cron:5-1 * * * *"} {
+		if _, err := todo.ParseRecurrence(rule); !errors.Is(err, todo.ErrInvalidRecurrence) {
+			t.Errorf("Rule %q: expected error %q, got %v instead.", rule, todo.ErrInvalidRecurrence, err)
+		}
+	}
+}
+
+func TestCompleteRecurring(t *testing.T) {
+	weekly, err := todo.ParseRecurrence("weekly")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	afterDaily, err := todo.ParseRecurrence("after:daily")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	y, m, d := time.Now().Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Weekly report", todo.WithDue(today.AddDate(0, 0, -1)), todo.WithRecurrence(weekly), todo.WithTags("work"))
+	l.Add("Water plants", todo.WithDue(today.AddDate(0, 0, -10)), todo.WithRecurrence(afterDaily))
+
+	before := slices.Clone(l)
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	change := todo.NewChange(todo.OpComplete, 1, before, l)
+
+	if err := l.Complete(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 4 {
+		t.Fatalf("Expected %d tasks, got %d instead.", 4, len(l))
+	}
+
+	next := l[2]
+	if next.Task != "Weekly report" || next.Done || next.Repeat != "weekly" || !next.Due.Equal(today.AddDate(0, 0, 6)) {
+		t.Errorf("Unexpected next occurrence %+v", next)
+	}
+
+	if len(next.Tags) != 1 || next.Tags[0] != "work" {
+		t.Errorf("Expected tags to be copied, got %v instead.", next.Tags)
+	}
+
+	if !l[3].Due.Equal(today.AddDate(0, 0, 1)) {
+		t.Errorf("Expected due date %v, got %v instead.", today.AddDate(0, 0, 1), l[3].Due)
+	}
+
+	if len(change.Related) != 1 || change.Related[0].After == nil || change.Related[0].After.ID != next.ID {
+		t.Errorf("Expected change to record spawned item %d, got %+v instead.", next.ID, change.Related)
+	}
+
+	if err := l.Complete(1); err != nil || len(l) != 4 {
+		t.Errorf("Completing a done task should not spawn again: %v, %d tasks", err, len(l))
+	}
+}
+
+func TestCompleteMonthlyKeepsDay(t *testing.T) {
+	monthly, err := todo.ParseRecurrence("monthly")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Far enough ahead for no occurrence to be skipped as missed.
+	due := time.Date(2099, time.January, 31, 0, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Pay rent", todo.WithDue(due), todo.WithRecurrence(monthly))
+
+	expected := []time.Time{
+		time.Date(2099, time.February, 28, 0, 0, 0, 0, time.Local),
+		time.Date(2099, time.March, 31, 0, 0, 0, 0, time.Local),
+		time.Date(2099, time.April, 30, 0, 0, 0, 0, time.Local),
+		time.Date(2099, time.May, 31, 0, 0, 0, 0, time.Local),
+	}
+
+	for _, exp := range expected {
+		last := l[len(l)-1]
+		if err := l.Complete(last.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if next := l[len(l)-1]; !next.Due.Equal(exp) {
+			t.Errorf("Expected due date %v, got %v instead.", exp, next.Due)
+		}
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	weekdays, err := todo.ParseRecurrence("cron:0 9 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := todo.List{}
+	l.Add("Plain task")
+	l.Add("Ship release", todo.WithPriority(todo.PriorityHigh),
+		todo.WithDue(time.Date(2024, time.May, 1, 0, 0, 0, 0, time.Local)),
+		todo.WithTags("work", "@office"))
+	l.Add("Standup", todo.WithRecurrence(weekdays), todo.WithPriority(todo.PriorityLow))
+	l.Add("Done task, with comma", todo.WithPriority(todo.PriorityMedium))
+
+	if err := l.Edit(3, "Standup"); err != nil {
+		t.Fatal(err)
+	}
+	l[3].Done = true
+	l[3].CompletedAt = time.Now()
+
+	if err := l.AddSubtask(4, "Write changelog", todo.WithTags("docs")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{todo.FormatTodoTxt, todo.FormatCSV, todo.FormatMarkdown} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			if err := l.Export(&buf, format); err != nil {
+				t.Fatal(err)
+			}
+
+			imported := todo.List{}
+			n, err := imported.Import(bytes.NewReader(buf.Bytes()), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n != len(l) {
+				t.Fatalf("Expected %d imported tasks, got %d instead.\n%s", len(l), n, buf.String())
+			}
+
+			if l.String() != imported.String() {
+				t.Errorf("Expected %q, got %q instead.", l.String(), imported.String())
+			}
+
+			merged := slices.Clone(l)
+			if n, err := merged.Import(bytes.NewReader(buf.Bytes()), format); err != nil || n != 0 {
+				t.Errorf("Expected duplicates to be skipped, got %d imported, %v", n, err)
+			}
+
+			shifted := todo.List{}
+			shifted.Add("Other")
+			if _, err := shifted.Import(bytes.NewReader(buf.Bytes()), format); err != nil {
+				t.Fatal(err)
+			}
+
+			tree := "[X] 5: Done task, with comma (medium)\n  [ ] 6: Write changelog #docs\n"
+			if !strings.Contains(shifted.String(), tree) {
+				t.Errorf("Expected subtask to follow its parent %q, got %q instead.", tree, shifted.String())
+			}
+		})
+	}
+}
+
+func TestImportTodoTxt(t *testing.T) {
+	input := `(A) 2024-05-01 Call mom +family @phone due:2024-05-03
+x 2024-05-02 2024-04-30 Pay bills http://bank.example pri:B
+
+2024-05-01 Water plants rec:after:weekly:mon,thu
+`
+
+	l := todo.List{}
+	n, err := l.Import(strings.NewReader(input), todo.FormatTodoTxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 3 {
+		t.Fatalf("Expected %d imported tasks, got %d instead.", 3, n)
+	}
+
+	expected := "[ ] 1: Call mom (high) due:2024-05-03 #family #@phone\n" +
+		"[X] 2: Pay bills http://bank.example (medium)\n" +
+		"[ ] 3: Water plants repeat:after:weekly:mon,thu\n"
+
+	if res := l.String(); res != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, res)
+	}
+
+	if _, err := l.Import(strings.NewReader("x 2024-05-02\n"), todo.FormatTodoTxt); !errors.Is(err, todo.ErrBlankTask) {
+		t.Errorf("Expected error %q, got %v instead.", todo.ErrBlankTask, err)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+var ErrCheck = errors.New("Found problems")
+
+// diagnostic is a problem found in a Markdown file.
+type diagnostic struct {
+	file string
+	line int
+	msg  string
+}
+
+func (d diagnostic) String() string {
+	return fmt.Sprintf("%s:%d: %s", d.file, d.line, d.msg)
+}
+
+// checker checks Markdown files, caching the anchors of the files their
+// links point to.
+type checker struct {
+	anchors map[string]map[string]bool
+}
+
+func newChecker() *checker {
+	return &checker{anchors: map[string]map[string]bool{}}
+}
+
+// check prints a diagnostic for every broken link, missing image or anchor,
+// duplicate heading ID and skipped heading level in files, returning
+// ErrCheck when there are any.
+func check(files []string, out io.Writer) error {
+	c := newChecker()
+	problems := 0
+
+	for _, fname := range files {
+		diags, err := c.checkFile(fname)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range diags {
+			fmt.Fprintln(out, d)
+		}
+
+		problems += len(diags)
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%w: %d", ErrCheck, problems)
+	}
+
+	return nil
+}
+
+// parsedFile is a Markdown file parsed for checking.
+type parsedFile struct {
+	src   []byte
+	ast   *blackfriday.Node
+	lines []int
+	// offset is the number of front matter lines before src.
+	offset int
+}
+
+func parseFile(fname string) (*parsedFile, error) {
+	input, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	input = bytes.ReplaceAll(input, []byte("\r\n"), []byte("\n"))
+
+	_, body := splitFrontMatter(input)
+
+	return &parsedFile{
+		src:    body,
+		ast:    blackfriday.New(blackfriday.WithExtensions(extensions)).Parse(body),
+		lines:  headingLines(body),
+		offset: bytes.Count(input[:len(input)-len(body)], []byte("\n")),
+	}, nil
+}
+
+func (c *checker) checkFile(fname string) ([]diagnostic, error) {
+	f, err := parseFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	diags := []diagnostic{}
+	report := func(line int, format string, a ...any) {
+		diags = append(diags, diagnostic{fname, line + f.offset, fmt.Sprintf(format, a...)})
+	}
+
+	firstLine := map[string]int{}
+	headings, level := 0, 0
+	type target struct {
+		dest  string
+		line  int
+		image bool
+	}
+	targets := []target{}
+	cursor := 0
+
+	f.ast.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering {
+			return blackfriday.GoToNext
+		}
+
+		switch n.Type {
+		case blackfriday.Heading:
+			if n.IsTitleblock {
+				break
+			}
+
+			line := 0
+			if headings < len(f.lines) {
+				line = f.lines[headings]
+			}
+			headings++
+
+			if level > 0 && n.Level > level+1 {
+				report(line, "heading level skipped from h%d to h%d", level, n.Level)
+			}
+			level = n.Level
+
+			if n.HeadingID == "" {
+				break
+			}
+
+			if first, ok := firstLine[n.HeadingID]; ok {
+				report(line, "duplicate heading ID %q, first used on line %d", n.HeadingID, first+f.offset)
+			} else {
+				firstLine[n.HeadingID] = line
+			}
+
+		case blackfriday.Link, blackfriday.Image:
+			if n.LinkData.NoteID != 0 {
+				break
+			}
+
+			dest := string(n.LinkData.Destination)
+
+			var line int
+			line, cursor = locate(f.src, dest, cursor)
+
+			targets = append(targets, target{dest, line, n.Type == blackfriday.Image})
+		}
+
+		return blackfriday.GoToNext
+	})
+
+	c.anchors[absPath(fname)] = f.anchors()
+
+	for _, t := range targets {
+		if msg := c.checkTarget(fname, t.dest, t.image); msg != "" {
+			report(t.line, "%s", msg)
+		}
+	}
+
+	slices.SortStableFunc(diags, func(a, b diagnostic) int {
+		return a.line - b.line
+	})
+
+	return diags, nil
+}
+
+// checkTarget returns what is wrong with a link or image destination found
+// in fname, or an empty string when nothing is. Remote URLs are not checked.
+func (c *checker) checkTarget(fname, dest string, image bool) string {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return fmt.Sprintf("invalid link %q", dest)
+	}
+
+	if u.Scheme != "" || u.Host != "" {
+		return ""
+	}
+
+	target := fname
+	if u.Path != "" {
+		target = filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(fname), target)
+		}
+
+		if _, err := os.Stat(target); err != nil {
+			if image {
+				return fmt.Sprintf("missing image %q", dest)
+			}
+			return fmt.Sprintf("broken link %q", dest)
+		}
+	}
+
+	if u.Fragment == "" || filepath.Ext(target) != ".md" {
+		return ""
+	}
+
+	anchors, err := c.fileAnchors(target)
+	if err != nil {
+		return fmt.Sprintf("cannot check anchor of %q: %v", dest, err)
+	}
+
+	if !anchors[u.Fragment] {
+		return fmt.Sprintf("missing anchor %q", dest)
+	}
+
+	return ""
+}
+
+func (c *checker) fileAnchors(fname string) (map[string]bool, error) {
+	if anchors, ok := c.anchors[absPath(fname)]; ok {
+		return anchors, nil
+	}
+
+	f, err := parseFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	c.anchors[absPath(fname)] = f.anchors()
+
+	return c.anchors[absPath(fname)], nil
+}
+
+// anchors returns the fragments that can be linked to in the file: the
+// rendered heading IDs and the ids and names of HTML elements.
+func (f *parsedFile) anchors() map[string]bool {
+	anchors := map[string]bool{}
+	ids := map[string]int{}
+
+	f.ast.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		switch {
+		case !entering:
+		case n.Type == blackfriday.Heading && n.HeadingID != "":
+			anchors[uniqueID(ids, n.HeadingID)] = true
+		case n.Type == blackfriday.HTMLBlock || n.Type == blackfriday.HTMLSpan:
+			for _, m := range htmlAnchorRe.FindAllSubmatch(n.Literal, -1) {
+				anchors[string(m[1])] = true
+			}
+		}
+
+		return blackfriday.GoToNext
+	})
+
+	return anchors
+}
+
+var (
+	htmlAnchorRe = regexp.MustCompile(`\b(?:id|name)="([^"]+)"`)
+	atxRe        = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+	setextRe     = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	fenceRe      = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// headingLines returns the line numbers of the headings in src, in order,
+// since the parser does not keep track of them.
+func headingLines(src []byte) []int {
+	lines := []int{}
+	fence := ""
+	prevText := false
+
+	for idx, line := range strings.Split(string(src), "\n") {
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			switch fence {
+			case "":
+				fence = m[1]
+			case m[1]:
+				fence = ""
+			}
+
+			prevText = false
+			continue
+		}
+
+		if fence != "" {
+			continue
+		}
+
+		switch {
+		case atxRe.MatchString(line):
+			lines = append(lines, idx+1)
+			prevText = false
+		case prevText && setextRe.MatchString(line):
+			lines = append(lines, idx)
+			prevText = false
+		default:
+			prevText = strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "    ")
+		}
+	}
+
+	return lines
+}
+
+// locate returns the line of the first occurrence of dest in src at or
+// after offset, and the offset to continue from. Destinations defined by
+// reference may come before offset, so those are searched from the start.
+func locate(src []byte, dest string, offset int) (int, int) {
+	if idx := bytes.Index(src[offset:], []byte(dest)); idx >= 0 {
+		idx += offset
+		return bytes.Count(src[:idx], []byte("\n")) + 1, idx + len(dest)
+	}
+
+	if idx := bytes.Index(src, []byte(dest)); idx >= 0 {
+		return bytes.Count(src[:idx], []byte("\n")) + 1, offset
+	}
+
+	return 0, offset
+}
+
+func absPath(fname string) string {
+	if abs, err := filepath.Abs(fname); err == nil {
+		return abs
+	}
+
+	return fname
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"doc.md": `---
+title: Doc
+---
+# Doc
+
+See [setup](#setup), [usage](#usage), [other](other.md#usage)
+and [missing](other.md#nope) or [gone](gone.md).
+
+![logo](logo.png) ![chart](img/chart.png) [remote](https://example.com/x.md)
+
+### Setup
+
+` + "```" + `
+# not a heading
+` + "```" + `
+
+Setup
+-----
+
+<a id="usage"></a>
+`,
+		"other.md": "# Other\n\n## Usage\n",
+		"logo.png": "PNG",
+		"ok.md":    "# OK\n\n## Part\n\nBack to [the doc](doc.md#setup-1).\n",
+	})
+
+	testCases := []struct {
+		name     string
+		file     string
+		expected []string
+	}{
+		{"Problems", "doc.md", []string{
+			"doc.md:7: missing anchor \"other.md#nope\"",
+			"doc.md:7: broken link \"gone.md\"",
+			"doc.md:9: missing image \"img/chart.png\"",
+			"doc.md:11: heading level skipped from h1 to h3",
+			"doc.md:17: duplicate heading ID \"setup\", first used on line 11",
+		}},
+		{"No Problems", "ok.md", []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := check([]string{filepath.Join(dir, tc.file)}, &out)
+
+			if len(tc.expected) == 0 {
+				if err != nil {
+					t.Fatalf("Expected no error, got %q:\n%s", err, out.String())
+				}
+			} else if !errors.Is(err, ErrCheck) {
+				t.Fatalf("Expected error %q, got %v", ErrCheck, err)
+			}
+
+			result := []string{}
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				if line != "" {
+					result = append(result, strings.TrimPrefix(line, dir+string(filepath.Separator)))
+				}
+			}
+
+			if strings.Join(result, "\n") != strings.Join(tc.expected, "\n") {
+				t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(tc.expected, "\n"), strings.Join(result, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// splitFrontMatter separates the front matter at the top of a Markdown
+// file from its body. Front matter is a YAML mapping fenced by "---" lines
+// or a TOML table fenced by "+++" lines. Anything else, such as a file
+// opening with a "---" horizontal rule, is returned as is, with empty
+// metadata.
+func splitFrontMatter(input []byte) (map[string]any, []byte) {
+	fences := []struct {
+		fence     string
+		unmarshal func([]byte, any) error
+	}{
+		{"---", yaml.Unmarshal},
+		{"+++", toml.Unmarshal},
+	}
+
+	normalized := bytes.ReplaceAll(input, []byte("\r\n"), []byte("\n"))
+
+	for _, f := range fences {
+		rest, ok := bytes.CutPrefix(normalized, []byte(f.fence+"\n"))
+		if !ok {
+			continue
+		}
+
+		block, body, ok := cutFence(rest, f.fence)
+		if !ok {
+			break
+		}
+
+		meta := map[string]any{}
+		if err := f.unmarshal(block, &meta); err != nil {
+			break
+		}
+
+		return meta, body
+	}
+
+	return map[string]any{}, input
+}
+
+// cutFence splits data around the first line made of fence alone.
+func cutFence(data []byte, fence string) ([]byte, []byte, bool) {
+	if rest, ok := bytes.CutPrefix(data, []byte(fence+"\n")); ok {
+		return nil, rest, true
+	}
+
+	block, body, ok := bytes.Cut(data, []byte("\n"+fence+"\n"))
+	if ok {
+		return block, body, true
+	}
+
+	if block, ok := bytes.CutSuffix(data, []byte("\n"+fence)); ok {
+		return block, nil, true
+	}
+
+	return nil, nil, false
+}
+
+// metaString returns the metadata value of key when it is a string.
+func metaString(meta map[string]any, key string) string {
+	s, _ := meta[key].(string)
+	return s
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/russross/blackfriday/v2"
+)
+
+// highlightStyle is the chroma style used for fenced code blocks.
+const highlightStyle = "github"
+
+// highlightRenderer renders fenced code blocks tagged with a known language
+// as syntax highlighted HTML, with inline styles so it needs no stylesheet.
+// Every other node is rendered by the embedded HTML renderer.
+type highlightRenderer struct {
+	*blackfriday.HTMLRenderer
+
+	formatter *chromahtml.Formatter
+	style     *chroma.Style
+}
+
+func newHighlightRenderer(r *blackfriday.HTMLRenderer) *highlightRenderer {
+	return &highlightRenderer{
+		HTMLRenderer: r,
+		formatter:    chromahtml.New(chromahtml.TabWidth(4)),
+		style:        styles.Get(highlightStyle),
+	}
+}
+
+func (r *highlightRenderer) RenderNode(w io.Writer, n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	if n.Type == blackfriday.CodeBlock {
+		if r.highlight(w, n) {
+			return blackfriday.GoToNext
+		}
+	}
+
+	return r.HTMLRenderer.RenderNode(w, n, entering)
+}
+
+// highlight writes the highlighted code block, reporting false when its
+// language is missing or unknown.
+func (r *highlightRenderer) highlight(w io.Writer, n *blackfriday.Node) bool {
+	lang, _, _ := strings.Cut(string(n.CodeBlockData.Info), " ")
+	if lang == "" {
+		return false
+	}
+
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		return false
+	}
+
+	it, err := chroma.Coalesce(lexer).Tokenise(nil, string(n.Literal))
+	if err != nil {
+		return false
+	}
+
+	var buf bytes.Buffer
+	if err := r.formatter.Format(&buf, r.style, it); err != nil {
+		return false
+	}
+
+	w.Write(buf.Bytes())
+	w.Write([]byte("\n"))
+
+	return true
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var (
+	imgSrcRe     = regexp.MustCompile(`(<img\b[^>]*?\bsrc=")([^"]*)(")`)
+	stylesheetRe = regexp.MustCompile(`<link\b[^>]*\brel="stylesheet"[^>]*>`)
+	hrefRe       = regexp.MustCompile(`\bhref="([^"]*)"`)
+)
+
+// inlineAssets makes htmlData self-contained: local images become data URIs
+// and local stylesheets are copied into style elements. Relative paths are
+// resolved from dir. Remote assets are left alone.
+func inlineAssets(htmlData []byte, dir string) ([]byte, error) {
+	var err error
+
+	htmlData = imgSrcRe.ReplaceAllFunc(htmlData, func(m []byte) []byte {
+		parts := imgSrcRe.FindSubmatch(m)
+
+		fname, ok := localPath(string(parts[2]), dir)
+		if !ok || err != nil {
+			return m
+		}
+
+		var data []byte
+		if data, err = os.ReadFile(fname); err != nil {
+			return m
+		}
+
+		return bytes.Join([][]byte{parts[1], []byte(dataURI(fname, data)), parts[3]}, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	htmlData = stylesheetRe.ReplaceAllFunc(htmlData, func(m []byte) []byte {
+		href := hrefRe.FindSubmatch(m)
+		if href == nil || err != nil {
+			return m
+		}
+
+		fname, ok := localPath(string(href[1]), dir)
+		if !ok {
+			return m
+		}
+
+		var css []byte
+		if css, err = os.ReadFile(fname); err != nil {
+			return m
+		}
+
+		return []byte("<style>\n" + string(css) + "\n</style>")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return htmlData, nil
+}
+
+// localPath returns the file an escaped src or href attribute points to,
+// if it is a relative or absolute path rather than a URL.
+func localPath(attr, dir string) (string, bool) {
+	u, err := url.Parse(html.UnescapeString(attr))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+
+	fname := filepath.FromSlash(u.Path)
+	if !filepath.IsAbs(fname) {
+		fname = filepath.Join(dir, fname)
+	}
+
+	return fname, true
+}
+
+func dataURI(fname string, data []byte) string {
+	mimeType := mime.TypeByExtension(filepath.Ext(fname))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
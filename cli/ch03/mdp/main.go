@@ -9,19 +9,29 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"time"
-
-	"github.com/microcosm-cc/bluemonday"
-	"github.com/russross/blackfriday/v2"
 )
 
+// printStyle starts every top level section on a new page when printing,
+// or saving as PDF, and keeps code, tables and images from being split.
+const printStyle = `<style>
+      @media print {
+        h1 { break-before: page; }
+        h1:first-of-type { break-before: avoid; }
+        h1, h2, h3, h4, h5, h6 { break-after: avoid; }
+        pre, table, img { break-inside: avoid; }
+      }
+    </style>`
+
 const defaultTemplate = `
 <!DOCTYPE html>
 <html>
   <head>
     <meta http-equiv="content-type" content="text/html; charset=utf-8">
     <title>{{.Title}}</title>
+    ` + printStyle + `
   </head>
   <body>
 	{{.Body}}
@@ -31,88 +41,198 @@ const defaultTemplate = `
 type content struct {
 	Title string
 	Body  template.HTML
+	Meta  map[string]any
+	TOC   []heading
+	Nav   []navLink
+}
+
+type config struct {
+	tName   string
+	outName string
+	preview bool
+	inline  bool
+	// fragment renders only the body, without the page template.
+	fragment bool
 }
 
 func main() {
-	filename := flag.String("file", "", "Markdown file to preview")
+	filename := flag.String("file", "", "Markdown file to preview, - or none to read STDIN")
 	preview := flag.Bool("preview", false, "Auto preview the file")
 	tName := flag.String("t", "", "Alternative template name")
+	outName := flag.String("out", "", "Write the HTML to this file instead of a temporary one, - for STDOUT")
+	inline := flag.Bool("inline", false, "Embed local images and stylesheets in the HTML")
+	fragment := flag.Bool("fragment", false, "Output only the HTML body, without the page template")
+	addr := flag.String("serve", "", "Serve a live reloading preview on this address, like :3000 for localhost only")
+	siteDir := flag.String("site", "", "Render every Markdown file in this directory to a static site")
+	dest := flag.String("dest", "site", "Output directory of the static site")
+	checkFiles := flag.Bool("check", false, "Report broken links, anchors, images and headings in the files given as arguments")
 	flag.Parse()
 
+	if *checkFiles {
+		files := flag.Args()
+		if *filename != "" {
+			files = append([]string{*filename}, files...)
+		}
+
+		if err := check(files, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if *siteDir != "" {
+		if err := buildSite(*siteDir, *dest, *tName, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if *filename == "" && stdinPiped() {
+		*filename = "-"
+	}
+
 	if *filename == "" {
 		flag.Usage()
 		return
 	}
 
-	if err := run(*filename, *tName, os.Stdout, *preview); err != nil {
+	if *addr != "" {
+		if err := serve(*addr, *filename, *tName); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	cfg := config{
+		tName:    *tName,
+		outName:  *outName,
+		preview:  *preview,
+		inline:   *inline,
+		fragment: *fragment,
+	}
+
+	if err := run(*filename, cfg, os.Stdin, os.Stdout); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run(filename, tName string, out io.Writer, preview bool) error {
-	input, err := os.ReadFile(filename)
+// stdinPiped reports whether STDIN is a pipe or file rather than a terminal.
+func stdinPiped() bool {
+	info, err := os.Stdin.Stat()
 	if err != nil {
-		return err
+		return false
 	}
 
-	htmlData, err := parseContent(input, tName)
-	if err != nil {
-		return err
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// run renders filename, or in when it is "-", to cfg.outName. When that is
+// "-" the HTML is written to out. Without an output file the HTML goes to
+// out for STDIN, and otherwise to a temporary file whose name is printed to
+// out and which is removed after previewing it.
+func run(filename string, cfg config, in io.Reader, out io.Writer) error {
+	dir := "."
+
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		in, dir = f, filepath.Dir(filename)
 	}
 
-	temp, err := os.CreateTemp("", "mdp*.html")
-	if err != nil {
+	var buf bytes.Buffer
+	if err := parseContent(in, &buf, cfg); err != nil {
 		return err
 	}
-	if err := temp.Close(); err != nil {
+
+	htmlData := buf.Bytes()
+
+	if cfg.inline {
+		var err error
+		htmlData, err = inlineAssets(htmlData, dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	outName := cfg.outName
+	if outName == "" && filename == "-" && !cfg.preview {
+		outName = "-"
+	}
+
+	if outName == "-" {
+		_, err := out.Write(htmlData)
 		return err
 	}
 
-	outName := temp.Name()
+	if outName == "" {
+		temp, err := os.CreateTemp("", "mdp*.html")
+		if err != nil {
+			return err
+		}
+		if err := temp.Close(); err != nil {
+			return err
+		}
+
+		outName = temp.Name()
 
-	fmt.Fprintln(out, outName)
+		fmt.Fprintln(out, outName)
+
+		if cfg.preview {
+			defer os.Remove(outName)
+		}
+	}
 
 	if err := saveHTML(outName, htmlData); err != nil {
 		return err
 	}
 
-	if !preview {
+	if !cfg.preview {
 		return nil
 	}
 
-	defer os.Remove(outName)
-
 	return previewFile(outName)
 }
 
-func parseContent(input []byte, tName string) ([]byte, error) {
-	output := blackfriday.Run(input)
-	body := bluemonday.UGCPolicy().SanitizeBytes(output)
-
-	templ, err := template.New("mdp").Parse(defaultTemplate)
+// parseContent renders the Markdown read from r to w, as a page using the
+// template selected by cfg or as an HTML fragment.
+func parseContent(r io.Reader, w io.Writer, cfg config) error {
+	input, err := io.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if tName != "" {
-		templ, err = template.ParseFiles(tName)
-		if err != nil {
-			return nil, err
-		}
+	meta, body := splitFrontMatter(input)
+
+	doc := renderDocument(body, renderOptions{})
+
+	if cfg.fragment {
+		_, err := io.WriteString(w, string(doc.Body))
+		return err
+	}
+
+	templ, err := loadTemplate("mdp", defaultTemplate, cfg.tName)
+	if err != nil {
+		return err
 	}
 
 	c := content{
 		Title: "Markdown Preview Tool",
-		Body:  template.HTML(body),
+		Body:  doc.Body,
+		Meta:  meta,
 	}
 
-	var buffer bytes.Buffer
-
-	if err := templ.Execute(&buffer, c); err != nil {
-		return nil, err
+	if title := metaString(meta, "title"); title != "" {
+		c.Title = title
 	}
 
-	return buffer.Bytes(), nil
+	return templ.Execute(w, c)
 }
 
 func saveHTML(outName string, data []byte) error {
@@ -2,7 +2,11 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -13,16 +17,19 @@ const (
 )
 
 func TestParseContent(t *testing.T) {
-	input, err := os.ReadFile(inputFile)
+	input, err := os.Open(inputFile)
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer input.Close()
 
-	result, err := parseContent(input, "")
-	if err != nil {
+	var buf bytes.Buffer
+	if err := parseContent(input, &buf, config{}); err != nil {
 		t.Fatal(err)
 	}
 
+	result := buf.Bytes()
+
 	expected, err := os.ReadFile(goldenFile)
 	if err != nil {
 		t.Fatal(err)
@@ -38,7 +45,7 @@ func TestParseContent(t *testing.T) {
 func TestRun(t *testing.T) {
 	var mockStdOut bytes.Buffer
 
-	if err := run(inputFile, "", &mockStdOut, false); err != nil {
+	if err := run(inputFile, config{}, nil, &mockStdOut); err != nil {
 		t.Fatal(err)
 	}
 
@@ -62,3 +69,103 @@ func TestRun(t *testing.T) {
 
 	os.Remove(resultFile)
 }
+
+func TestRunStdin(t *testing.T) {
+	input := "# Piped\n\nSome *text*.\n"
+
+	testCases := []struct {
+		name     string
+		cfg      config
+		expected string
+	}{
+		{"Page", config{}, "<title>Markdown Preview Tool</title>"},
+		{"Fragment", config{fragment: true}, "<h1 id=\"piped\">Piped</h1>\n\n<p>Some <em>text</em>.</p>\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mockStdOut bytes.Buffer
+
+			if err := run("-", tc.cfg, strings.NewReader(input), &mockStdOut); err != nil {
+				t.Fatal(err)
+			}
+
+			result := mockStdOut.String()
+
+			if tc.cfg.fragment {
+				if result != tc.expected {
+					t.Errorf("Expected %q, got %q", tc.expected, result)
+				}
+				return
+			}
+
+			if !strings.Contains(result, tc.expected) || !strings.Contains(result, `<h1 id="piped">`) {
+				t.Errorf("Expected a page with %q, got:\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRunOut(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"doc.md":        "# Report\n\n![chart](img/chart.png)\n![logo](https://example.com/logo.png)\n",
+		"img/chart.png": "\x89PNG\r\n\x1a\n",
+		"page.tmpl":     `<link rel="stylesheet" href="style.css">{{.Body}}`,
+		"style.css":     "h1 { color: red; }",
+	})
+
+	testCases := []struct {
+		name     string
+		cfg      config
+		expected []string
+	}{
+		{"Out", config{}, []string{`<img src="img/chart.png" alt="chart"/>`}},
+		{"Inline", config{inline: true}, []string{
+			`<img src="data:image/png;base64,iVBORw0KGgo=" alt="chart"/>`,
+			`<img src="https://example.com/logo.png" alt="logo"/>`,
+		}},
+		{"Inline Template", config{inline: true, tName: filepath.Join(dir, "page.tmpl")}, []string{
+			"<style>\nh1 { color: red; }\n</style>",
+			`<img src="data:image/png;base64,`,
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mockStdOut bytes.Buffer
+
+			tc.cfg.outName = filepath.Join(t.TempDir(), "out.html")
+
+			if err := run(filepath.Join(dir, "doc.md"), tc.cfg, nil, &mockStdOut); err != nil {
+				t.Fatal(err)
+			}
+
+			if mockStdOut.Len() != 0 {
+				t.Errorf("Expected no output, got %q", mockStdOut.String())
+			}
+
+			result, err := os.ReadFile(tc.cfg.outName)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, exp := range tc.expected {
+				if !strings.Contains(string(result), exp) {
+					t.Errorf("Expected %q in:\n%s", exp, result)
+				}
+			}
+		})
+	}
+
+	t.Run("Missing Image", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{"broken.md": "![gone](gone.png)\n"})
+
+		cfg := config{inline: true, outName: filepath.Join(t.TempDir(), "out.html")}
+
+		if err := run(filepath.Join(dir, "broken.md"), cfg, nil, io.Discard); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected error %q, got %v", fs.ErrNotExist, err)
+		}
+	})
+}
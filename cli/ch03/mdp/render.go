@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday/v2"
+)
+
+// heading is an entry of a page's table of contents.
+type heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// document is a Markdown file rendered to sanitized HTML.
+type document struct {
+	Title    string
+	Body     template.HTML
+	Headings []heading
+}
+
+type renderOptions struct {
+	// rewriteLinks points relative links to .md files at the .html page
+	// rendered from them.
+	rewriteLinks bool
+}
+
+// extensions are the Markdown extensions enabled on top of the common
+// ones, for GitHub flavoured Markdown. Task lists are handled separately.
+const extensions = blackfriday.CommonExtensions |
+	blackfriday.AutoHeadingIDs |
+	blackfriday.Footnotes
+
+// renderDocument renders input to sanitized HTML, giving every heading a
+// unique id and highlighting fenced code blocks.
+func renderDocument(input []byte, opts renderOptions) document {
+	r := newHighlightRenderer(blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+		Flags: blackfriday.CommonHTMLFlags | blackfriday.FootnoteReturnLinks,
+	}))
+
+	ast := blackfriday.New(blackfriday.WithRenderer(r), blackfriday.WithExtensions(extensions)).Parse(input)
+
+	doc := document{}
+	ids := map[string]int{}
+
+	ast.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering {
+			return blackfriday.GoToNext
+		}
+
+		switch n.Type {
+		case blackfriday.Heading:
+			if n.IsTitleblock {
+				break
+			}
+
+			text := nodeText(n)
+			if doc.Title == "" && n.Level == 1 {
+				doc.Title = text
+			}
+
+			if n.HeadingID != "" {
+				n.HeadingID = uniqueID(ids, n.HeadingID)
+				doc.Headings = append(doc.Headings, heading{n.Level, n.HeadingID, text})
+			}
+
+		case blackfriday.Item:
+			taskItem(n)
+
+		case blackfriday.Link:
+			if opts.rewriteLinks {
+				n.LinkData.Destination = []byte(mdToHTML(string(n.LinkData.Destination)))
+			}
+		}
+
+		return blackfriday.GoToNext
+	})
+
+	var buf bytes.Buffer
+
+	r.RenderHeader(&buf, ast)
+	ast.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		return r.RenderNode(&buf, n, entering)
+	})
+	r.RenderFooter(&buf, ast)
+
+	doc.Body = template.HTML(sanitizer().SanitizeBytes(buf.Bytes()))
+
+	return doc
+}
+
+var (
+	headingIDRe = regexp.MustCompile(`^[\p{L}\p{N}_.:-]+$`)
+	classRe     = regexp.MustCompile(`^[\w -]+$`)
+)
+
+// sanitizer returns the policy applied to the rendered Markdown. On top of
+// user generated content it keeps heading ids, code languages, footnotes, task
+// list checkboxes and the inline styles of highlighted code.
+func sanitizer() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(headingIDRe).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("class").Matching(classRe).OnElements("sup", "div", "a", "li", "code")
+
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+
+	p.AllowStyles("color", "background-color", "font-weight", "font-style", "text-decoration",
+		"display", "white-space", "padding", "margin").OnElements("pre", "span")
+
+	return p
+}
+
+// nodeText returns the plain text inside n.
+func nodeText(n *blackfriday.Node) string {
+	var sb strings.Builder
+
+	n.Walk(func(c *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && (c.Type == blackfriday.Text || c.Type == blackfriday.Code) {
+			sb.Write(c.Literal)
+		}
+
+		return blackfriday.GoToNext
+	})
+
+	return strings.TrimSpace(sb.String())
+}
+
+// uniqueID returns id, suffixed with a counter if it was already used in
+// the document, the way GitHub does.
+func uniqueID(ids map[string]int, id string) string {
+	n := ids[id]
+	ids[id]++
+
+	if n == 0 {
+		return id
+	}
+
+	unique := fmt.Sprintf("%s-%d", id, n)
+	ids[unique]++
+
+	return unique
+}
+
+// taskItem turns a list item starting with "[ ]" or "[x]" into an item of a
+// task list, with a checkbox in place of the marker.
+func taskItem(item *blackfriday.Node) {
+	text := item.FirstChild
+	for text != nil && text.Type != blackfriday.Text {
+		text = text.FirstChild
+	}
+
+	if text == nil || len(text.Literal) < 4 || text.Literal[3] != ' ' {
+		return
+	}
+
+	checkbox := ""
+	switch string(text.Literal[:3]) {
+	case "[ ]":
+		checkbox = `<input type="checkbox" disabled="">`
+	case "[x]", "[X]":
+		checkbox = `<input type="checkbox" checked="" disabled="">`
+	default:
+		return
+	}
+
+	text.Literal = text.Literal[3:]
+
+	box := blackfriday.NewNode(blackfriday.HTMLSpan)
+	box.Literal = []byte(checkbox)
+	text.InsertBefore(box)
+}
+
+// mdToHTML rewrites a relative link to a Markdown file into a link to the
+// page rendered from it, keeping any query or fragment.
+func mdToHTML(dest string) string {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || path.Ext(u.Path) != ".md" {
+		return dest
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, ".md") + ".html"
+
+	return u.String()
+}
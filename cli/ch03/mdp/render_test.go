@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderDocumentGFM(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+		absent   []string
+	}{
+		{
+			name:     "Highlight",
+			input:    "```go\nfunc main() {}\n```\n",
+			expected: []string{`<pre style="`, `<span style="color: `, ">func</span>"},
+		},
+		{
+			name:     "Unknown Language",
+			input:    "```nosuchlang\nsome code\n```\n",
+			expected: []string{"<pre><code class=\"language-nosuchlang\">some code\n</code></pre>"},
+		},
+		{
+			name:     "Table",
+			input:    "| a | b |\n|:--|--:|\n| 1 | 2 |\n",
+			expected: []string{"<table>", `<th align="left">a</th>`, `<td align="right">2</td>`},
+		},
+		{
+			name:  "Task List",
+			input: "- [ ] todo\n- [x] done\n- [link](x.md)\n",
+			expected: []string{
+				`<li><input type="checkbox" disabled=""> todo</li>`,
+				`<li><input type="checkbox" checked="" disabled=""> done</li>`,
+				`<li><a href="x.md" rel="nofollow">link</a></li>`,
+			},
+		},
+		{
+			name:     "Autolink",
+			input:    "See https://example.com now\n",
+			expected: []string{`<a href="https://example.com" rel="nofollow">https://example.com</a>`},
+		},
+		{
+			name:  "Footnotes",
+			input: "A claim[^1].\n\n[^1]: The source.\n",
+			expected: []string{
+				`<sup class="footnote-ref" id="fnref:1"><a href="#fn:1"`,
+				`<li id="fn:1">The source.`,
+				`<a class="footnote-return" href="#fnref:1"`,
+			},
+		},
+		{
+			name:     "Heading Anchors",
+			input:    "# Intro\n\n## Intro\n\n## Custom {#here}\n",
+			expected: []string{`<h1 id="intro">`, `<h2 id="intro-1">`, `<h2 id="here">`},
+		},
+		{
+			name:     "Sanitized",
+			input:    "<input type=\"text\" onfocus=\"x()\"> <span style=\"position: fixed\">x</span><script>x()</script>\n",
+			absent:   []string{"onfocus", "type=\"text\"", "position", "<script>"},
+			expected: []string{"<span>x</span>"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := string(renderDocument([]byte(tc.input), renderOptions{}).Body)
+
+			for _, exp := range tc.expected {
+				if !strings.Contains(body, exp) {
+					t.Errorf("Expected %q in:\n%s", exp, body)
+				}
+			}
+
+			for _, abs := range tc.absent {
+				if strings.Contains(body, abs) {
+					t.Errorf("Expected no %q in:\n%s", abs, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// watchInterval is how often the previewed file is checked for changes.
+var watchInterval = 500 * time.Millisecond
+
+const reloadScript = `<script>
+new EventSource("/events").onmessage = () => location.reload();
+</script>
+`
+
+// previewServer renders a Markdown file on every request and tells the
+// connected browsers to reload when the file changes.
+type previewServer struct {
+	filename string
+	tName    string
+
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+	modTime time.Time
+	size    int64
+}
+
+func newPreviewServer(filename, tName string) (*previewServer, error) {
+	s := &previewServer{
+		filename: filename,
+		tName:    tName,
+		clients:  map[chan struct{}]struct{}{},
+	}
+
+	if _, err := s.changed(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// routes serves the rendered file at /, reload events at /events and the
+// files next to the Markdown file, such as images, everywhere else.
+func (s *previewServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.render)
+	mux.HandleFunc("GET /events", s.events)
+	mux.Handle("GET /", http.FileServer(filesOnly{http.Dir(filepath.Dir(s.filename))}))
+
+	return mux
+}
+
+// filesOnly hides the directories of a file system, so the file server
+// never lists them.
+type filesOnly struct {
+	http.FileSystem
+}
+
+func (fsys filesOnly) Open(name string) (http.File, error) {
+	f, err := fsys.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if info.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+
+	return f, nil
+}
+
+// listenAddr keeps the preview on the loopback interface when addr names
+// no host, like :3000.
+func listenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+func (s *previewServer) render(w http.ResponseWriter, r *http.Request) {
+	f, err := os.Open(s.filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if err := parseContent(f, &buf, config{tName: s.tName}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(injectScript(buf.Bytes(), reloadScript))
+}
+
+// injectScript adds script to the end of the document body.
+func injectScript(htmlData []byte, script string) []byte {
+	idx := bytes.LastIndex(htmlData, []byte("</body>"))
+	if idx < 0 {
+		return append(htmlData, script...)
+	}
+
+	out := make([]byte, 0, len(htmlData)+len(script))
+	out = append(out, htmlData[:idx]...)
+	out = append(out, script...)
+
+	return append(out, htmlData[idx:]...)
+}
+
+func (s *previewServer) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	fmt.Fprint(w, ": watching\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-ch:
+			if _, err := fmt.Fprint(w, "data: reload\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// changed reports whether the file was modified since the last call.
+func (s *previewServer) changed() (bool, error) {
+	info, err := os.Stat(s.filename)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return false, nil
+	}
+
+	s.modTime, s.size = info.ModTime(), info.Size()
+
+	return true, nil
+}
+
+func (s *previewServer) broadcast() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// watch polls the file until ctx is done, sending a reload to the clients
+// whenever it changes. Errors, like the file being briefly missing while an
+// editor saves it, are logged and retried.
+func (s *previewServer) watch(ctx context.Context) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			changed, err := s.changed()
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+
+			if changed {
+				s.broadcast()
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func serve(addr, filename, tName string) error {
+	s, err := newPreviewServer(filename, tName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go s.watch(ctx)
+
+	addr = listenAddr(addr)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.routes(),
+		BaseContext:       func(_ net.Listener) context.Context { return ctx },
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+
+		<-c
+		// Ends the event streams, which would otherwise keep the server
+		// from shutting down.
+		cancel()
+
+		if err := srv.Shutdown(context.Background()); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+		close(done)
+	}()
+
+	log.Printf("Previewing %q on %s\n", filename, addr)
+
+	err = srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	<-done
+
+	return nil
+}
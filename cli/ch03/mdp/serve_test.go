@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupPreview(t *testing.T) (*previewServer, string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "README.md")
+
+	if err := os.WriteFile(fname, []byte("# Hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := newPreviewServer(fname, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+
+	return s, ts.URL, fname
+}
+
+func TestServeRender(t *testing.T) {
+	_, url, fname := setupPreview(t)
+
+	if err := os.WriteFile(filepath.Join(filepath.Dir(fname), "logo.txt"), []byte("logo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(fname), "img"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		code     int
+		expected []string
+	}{
+		{"Render", "/", http.StatusOK, []string{`<h1 id="hello">Hello</h1>`, `new EventSource("/events")`}},
+		{"Asset", "/logo.txt", http.StatusOK, []string{"logo"}},
+		{"NotFound", "/missing.png", http.StatusNotFound, nil},
+		{"NoListing", "/img/", http.StatusNotFound, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(url + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tc.code {
+				t.Fatalf("Expected status %d, got %q instead", tc.code, resp.Status)
+			}
+
+			for _, exp := range tc.expected {
+				if !bytes.Contains(body, []byte(exp)) {
+					t.Errorf("Expected body to contain %q, got %q instead", exp, body)
+				}
+			}
+		})
+	}
+}
+
+func TestServeReload(t *testing.T) {
+	s, url, fname := setupPreview(t)
+
+	resp, err := http.Get(url + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q instead", ct)
+	}
+
+	events := bufio.NewReader(resp.Body)
+	if _, err := events.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := s.changed(); err != nil || changed {
+		t.Fatalf("Expected no change, got %v, %v", changed, err)
+	}
+
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(fname, []byte("# Hello again\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(fname, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := s.changed()
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+
+	s.broadcast()
+
+	for {
+		line, err := events.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.HasPrefix(line, "data: reload") {
+			break
+		}
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	testCases := []struct {
+		addr     string
+		expected string
+	}{
+		{":3000", "127.0.0.1:3000"},
+		{"localhost:3000", "localhost:3000"},
+		{"0.0.0.0:3000", "0.0.0.0:3000"},
+		{"[::1]:3000", "[::1]:3000"},
+	}
+
+	for _, tc := range testCases {
+		if res := listenAddr(tc.addr); res != tc.expected {
+			t.Errorf("Expected %q, got %q instead", tc.expected, res)
+		}
+	}
+}
+
+func TestInjectScript(t *testing.T) {
+	res := injectScript([]byte("<body><p>x</p></body></html>"), "<script></script>")
+
+	if exp := "<body><p>x</p><script></script></body></html>"; string(res) != exp {
+		t.Errorf("Expected %q, got %q instead", exp, res)
+	}
+}
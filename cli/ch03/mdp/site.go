@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const siteTemplate = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta http-equiv="content-type" content="text/html; charset=utf-8">
+    <title>{{.Title}}</title>
+    <style>
+      body { display: flex; margin: 0; font-family: sans-serif; }
+      nav { min-width: 14rem; padding: 1rem; background: #f4f4f4; }
+      nav a.current { font-weight: bold; }
+      .meta { color: #666; }
+      .tag { padding: 0 .4em; border-radius: .3em; background: #eee; }
+      main { flex: 1; max-width: 70ch; padding: 1rem 2rem; }
+      .toc ul { padding-left: 1rem; list-style: none; }
+      .toc-h3 { margin-left: 1rem; }
+      .toc-h4, .toc-h5, .toc-h6 { margin-left: 2rem; }
+      @media print { nav, .toc { display: none; } }
+    </style>
+    ` + printStyle + `
+  </head>
+  <body>
+    <nav>
+      <ul>
+      {{- range .Nav}}
+        <li><a href="{{.URL}}"{{if .Current}} class="current"{{end}}>{{.Title}}</a></li>
+      {{- end}}
+      </ul>
+    </nav>
+    <main>
+      <p class="meta">
+        {{- with .Meta.author}}{{.}} · {{end}}
+        {{- with .Meta.date}}{{date "Jan 2, 2006" .}} · {{end}}
+        {{- readingTime .Body}} min read
+        {{- range .Meta.tags}} <span class="tag">{{.}}</span>{{end -}}
+      </p>
+      {{- if .TOC}}
+      <details class="toc" open>
+        <summary>Contents</summary>
+        <ul>
+        {{- range .TOC}}
+          <li class="toc-h{{.Level}}"><a href="#{{.ID}}">{{.Text}}</a></li>
+        {{- end}}
+        </ul>
+      </details>
+      {{- end}}
+      {{.Body}}
+    </main>
+  </body>
+</html>
+`
+
+// navLink is an entry of the navigation shared by the pages of a site.
+type navLink struct {
+	Title   string
+	URL     string
+	Current bool
+}
+
+type sitePage struct {
+	src  string
+	rel  string
+	meta map[string]any
+	doc  document
+	html string
+}
+
+// buildSite renders every Markdown file under src into an HTML page under
+// dest, keeping the directory layout, and copies the other files, such as
+// images, along. It prints the name of every page written to out.
+func buildSite(src, dest, tName string, out io.Writer) error {
+	templ, err := loadTemplate("site", siteTemplate, tName)
+	if err != nil {
+		return err
+	}
+
+	destAbs, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+
+	pages := []*sitePage{}
+	assets := []string{}
+
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if abs, _ := filepath.Abs(path); abs == destAbs {
+				return fs.SkipDir
+			}
+
+			if path != src && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if filepath.Ext(path) != ".md" {
+			assets = append(assets, rel)
+			return nil
+		}
+
+		pages = append(pages, &sitePage{
+			src:  path,
+			rel:  rel,
+			html: strings.TrimSuffix(rel, ".md") + ".html",
+		})
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pages {
+		input, err := os.ReadFile(p.src)
+		if err != nil {
+			return err
+		}
+
+		meta, body := splitFrontMatter(input)
+
+		p.meta = meta
+		p.doc = renderDocument(body, renderOptions{rewriteLinks: true})
+
+		switch {
+		case metaString(meta, "title") != "":
+			p.doc.Title = metaString(meta, "title")
+		case p.doc.Title == "":
+			p.doc.Title = strings.TrimSuffix(filepath.Base(p.rel), ".md")
+		}
+	}
+
+	for _, p := range pages {
+		c := content{
+			Title: p.doc.Title,
+			Body:  p.doc.Body,
+			Meta:  p.meta,
+			TOC:   p.doc.Headings,
+			Nav:   siteNav(pages, p),
+		}
+
+		var buf bytes.Buffer
+		if err := templ.Execute(&buf, c); err != nil {
+			return fmt.Errorf("%s: %w", p.src, err)
+		}
+
+		outName := filepath.Join(dest, p.html)
+		if err := os.MkdirAll(filepath.Dir(outName), 0755); err != nil {
+			return err
+		}
+
+		if err := saveHTML(outName, buf.Bytes()); err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, outName)
+	}
+
+	for _, rel := range assets {
+		if err := copyFile(filepath.Join(src, rel), filepath.Join(dest, rel)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// siteNav links every page of the site relative to the current one.
+func siteNav(pages []*sitePage, current *sitePage) []navLink {
+	nav := []navLink{}
+
+	for _, p := range pages {
+		url, err := filepath.Rel(filepath.Dir(current.html), p.html)
+		if err != nil {
+			url = p.html
+		}
+
+		nav = append(nav, navLink{
+			Title:   p.doc.Title,
+			URL:     filepath.ToSlash(url),
+			Current: p == current,
+		})
+	}
+
+	return nav
+}
+
+func copyFile(src, dest string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(dest, data, 0644)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, data := range files {
+		fname := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuildSite(t *testing.T) {
+	src := t.TempDir()
+	dest := filepath.Join(t.TempDir(), "out")
+
+	writeFiles(t, src, map[string]string{
+		"index.md":         "# Home\n\nSee the [guide](docs/guide.md#install) and [Go](https://go.dev/x.md).\n",
+		"docs/guide.md":    "---\ntitle: User Guide\n---\n# Guide\n\n## Install\n\n## Install\n\nBack [home](../index.md).\n",
+		"docs/untitled.md": "Just text.\n",
+		"docs/img.png":     "PNG",
+		".git/HEAD.md":     "# Hidden\n",
+	})
+
+	var out bytes.Buffer
+	if err := buildSite(src, dest, "", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(out.String(), "\n"); n != 3 {
+		t.Errorf("Expected 3 pages written, got %d:\n%s", n, out.String())
+	}
+
+	read := func(name string) string {
+		t.Helper()
+
+		data, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(data)
+	}
+
+	testCases := []struct {
+		name     string
+		page     string
+		expected []string
+	}{
+		{"Rewrite Links", "index.html", []string{
+			`href="docs/guide.html#install"`,
+			`href="https://go.dev/x.md"`,
+		}},
+		{"Title From H1", "index.html", []string{"<title>Home</title>"}},
+		{"Title From Front Matter", "docs/guide.html", []string{
+			"<title>User Guide</title>",
+			`href="../index.html"`,
+		}},
+		{"Title From Filename", "docs/untitled.html", []string{"<title>untitled</title>"}},
+		{"TOC", "docs/guide.html", []string{
+			`<h2 id="install">`,
+			`<h2 id="install-1">`,
+			`<a href="#install">Install</a>`,
+			`<a href="#install-1">Install</a>`,
+		}},
+		{"Nav", "docs/guide.html", []string{
+			`<a href="../index.html">Home</a>`,
+			`<a href="guide.html" class="current">User Guide</a>`,
+			`<a href="untitled.html">untitled</a>`,
+		}},
+		{"Assets", "docs/img.png", []string{"PNG"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page := read(tc.page)
+
+			for _, exp := range tc.expected {
+				if !strings.Contains(page, exp) {
+					t.Errorf("Expected %q in %s:\n%s", exp, tc.page, page)
+				}
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, ".git")); err == nil {
+		t.Error("Expected hidden directories to be skipped")
+	}
+}
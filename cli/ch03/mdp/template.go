@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// wordsPerMinute is the reading speed used to estimate reading time.
+const wordsPerMinute = 200
+
+var ErrDate = errors.New("Cannot format date")
+
+// loadTemplate parses the template file tName, or the text def when tName
+// is empty, with the helper functions available to templates. Partials
+// included by the template are looked up next to it.
+func loadTemplate(name, def, tName string) (*template.Template, error) {
+	if tName == "" {
+		return template.New(name).Funcs(templateFuncs(".")).Parse(def)
+	}
+
+	return template.New(filepath.Base(tName)).
+		Funcs(templateFuncs(filepath.Dir(tName))).
+		ParseFiles(tName)
+}
+
+// templateFuncs returns the functions available to templates:
+//
+//	date "Jan 2, 2006" .Meta.date    formats a time or a date string
+//	wordCount .Body                  counts the words of text or HTML
+//	readingTime .Body                estimates the minutes to read it
+//	include "partial.html" .         renders the template file partial.html
+func templateFuncs(dir string) template.FuncMap {
+	funcs := template.FuncMap{
+		"date":        formatDate,
+		"wordCount":   wordCount,
+		"readingTime": readingTime,
+	}
+
+	funcs["include"] = func(name string, data any) (template.HTML, error) {
+		fname := name
+		if !filepath.IsAbs(fname) {
+			fname = filepath.Join(dir, name)
+		}
+
+		t, err := template.New(filepath.Base(fname)).Funcs(funcs).ParseFiles(fname)
+		if err != nil {
+			return "", err
+		}
+
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return "", err
+		}
+
+		// The partial was escaped when it was executed.
+		return template.HTML(buf.String()), nil
+	}
+
+	return funcs
+}
+
+var dateLayouts = []string{time.RFC3339, time.DateTime, time.DateOnly}
+
+func formatDate(layout string, v any) (string, error) {
+	switch d := v.(type) {
+	case time.Time:
+		return d.Format(layout), nil
+
+	case string:
+		for _, l := range dateLayouts {
+			if t, err := time.Parse(l, d); err == nil {
+				return t.Format(layout), nil
+			}
+		}
+
+	case nil:
+		return "", nil
+	}
+
+	return "", fmt.Errorf("%w: %v", ErrDate, v)
+}
+
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// plainText returns the text of v, with the markup removed when it is HTML.
+func plainText(v any) string {
+	switch s := v.(type) {
+	case template.HTML:
+		return html.UnescapeString(tagRe.ReplaceAllString(string(s), " "))
+	case string:
+		return s
+	case nil:
+		return ""
+	}
+
+	return fmt.Sprint(v)
+}
+
+func wordCount(v any) int {
+	return len(strings.Fields(plainText(v)))
+}
+
+// readingTime returns the minutes it takes to read v, at least one.
+func readingTime(v any) int {
+	return max(1, (wordCount(v)+wordsPerMinute-1)/wordsPerMinute)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		title string
+		body  string
+	}{
+		{name: "None", input: "# Doc\n", body: "# Doc\n"},
+		{name: "YAML", input: "---\ntitle: Doc\ntags: [a, b]\n---\n# Doc\n", title: "Doc", body: "# Doc\n"},
+		{name: "YAML CRLF", input: "---\r\ntitle: Doc\r\n---\r\n# Doc\r\n", title: "Doc", body: "# Doc\n"},
+		{name: "TOML", input: "+++\ntitle = \"Doc\"\n+++\n# Doc\n", title: "Doc", body: "# Doc\n"},
+		{name: "Empty", input: "---\n---\n# Doc\n", body: "# Doc\n"},
+		{name: "Unclosed", input: "---\ntitle: Doc\n# Doc\n", body: "---\ntitle: Doc\n# Doc\n"},
+		{name: "Invalid", input: "+++\ntitle = \n+++\n", body: "+++\ntitle = \n+++\n"},
+		{name: "Rules", input: "---\nSome text.\n---\n# Doc\n", body: "---\nSome text.\n---\n# Doc\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta, body := splitFrontMatter([]byte(tc.input))
+
+			if title := metaString(meta, "title"); title != tc.title {
+				t.Errorf("Expected title %q, got %q", tc.title, title)
+			}
+
+			if string(body) != tc.body {
+				t.Errorf("Expected body %q, got %q", tc.body, body)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	date := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+	for _, v := range []any{date, "2024-03-05", "2024-03-05T00:00:00Z"} {
+		got, err := formatDate("Jan 2, 2006", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != "Mar 5, 2024" {
+			t.Errorf("Expected %q, got %q", "Mar 5, 2024", got)
+		}
+	}
+
+	if _, err := formatDate(time.DateOnly, "yesterday"); !errors.Is(err, ErrDate) {
+		t.Errorf("Expected error %q, got %v", ErrDate, err)
+	}
+
+	body := template.HTML("<p>One <em>two</em>&amp; three</p>")
+	if n := wordCount(body); n != 4 {
+		t.Errorf("Expected 4 words, got %d", n)
+	}
+
+	if n := readingTime(body); n != 1 {
+		t.Errorf("Expected 1 minute, got %d", n)
+	}
+
+	if n := readingTime(strings.Repeat("word ", 401)); n != 3 {
+		t.Errorf("Expected 3 minutes, got %d", n)
+	}
+}
+
+func TestParseContentMeta(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"doc.tmpl": `<title>{{.Title}}</title>` +
+			`{{include "byline.tmpl" .Meta}}` +
+			`{{range .Meta.tags}}[{{.}}]{{end}}` +
+			`{{wordCount .Body}} words`,
+		"byline.tmpl": `<p>{{.author}}, {{date "2006/01/02" .date}}</p>`,
+	})
+
+	input := "---\ntitle: Notes\nauthor: A & B\ndate: 2024-03-05\ntags: [go, cli]\n---\nSome notes here.\n"
+
+	var result strings.Builder
+	if err := parseContent(strings.NewReader(input), &result, config{tName: filepath.Join(dir, "doc.tmpl")}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "<title>Notes</title><p>A &amp; B, 2024/03/05</p>[go][cli]3 words"
+	if result.String() != exp {
+		t.Errorf("Expected %q, got %q", exp, result.String())
+	}
+}
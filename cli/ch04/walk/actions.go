@@ -8,25 +8,68 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 )
 
-func filterOut(path, ext, name string, minSize int64, info fs.FileInfo) bool {
-	if info.IsDir() || info.Size() < minSize {
+// filterOut reports whether the file at path should be skipped. Every
+// filter set in cfg has to match; the filters that take a list match when
+// any of their values does.
+func filterOut(path string, info fs.FileInfo, cfg config) bool {
+	if info.IsDir() || info.Size() < cfg.size {
 		return true
 	}
 
-	if ext != "" && filepath.Ext(path) != ext {
+	if cfg.maxSize > 0 && info.Size() > cfg.maxSize {
 		return true
 	}
 
-	if name != "" && !strings.Contains(info.Name(), name) {
+	if len(cfg.exts) > 0 && !slices.Contains(cfg.exts, filepath.Ext(path)) {
+		return true
+	}
+
+	if cfg.name != "" && !strings.Contains(info.Name(), cfg.name) {
+		return true
+	}
+
+	if len(cfg.globs) > 0 && !matchAny(cfg.globs, info.Name()) {
+		return true
+	}
+
+	if len(cfg.regexps) > 0 && !slices.ContainsFunc(cfg.regexps, func(re *regexp.Regexp) bool {
+		return re.MatchString(info.Name())
+	}) {
+		return true
+	}
+
+	modified := time.Since(info.ModTime())
+
+	if cfg.older > 0 && modified < cfg.older {
+		return true
+	}
+
+	if cfg.newer > 0 && modified > cfg.newer {
 		return true
 	}
 
 	return false
 }
 
+// excludeDir reports whether the directory with this name matches one of
+// the exclude patterns and should not be walked into.
+func excludeDir(name string, excludes []string) bool {
+	return matchAny(excludes, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, _ := filepath.Match(pattern, name)
+		return ok
+	})
+}
+
 func listFile(path string, out io.Writer) error {
 	_, err := fmt.Fprintln(out, path)
 	return err
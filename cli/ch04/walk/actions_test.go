@@ -2,7 +2,10 @@ package main
 
 import (
 	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
+	"time"
 )
 
 func TestFilterOut(t *testing.T) {
@@ -34,7 +37,66 @@ func TestFilterOut(t *testing.T) {
 			}
 
 			t.Log(tc.file, tc.ext, tc.filename, tc.minSize, info.Name())
-			f := filterOut(tc.file, tc.ext, tc.filename, tc.minSize, info)
+			cfg := config{name: tc.filename, size: tc.minSize}
+			if tc.ext != "" {
+				cfg.exts = []string{tc.ext}
+			}
+
+			f := filterOut(tc.file, info, cfg)
+
+			if f != tc.expected {
+				t.Errorf("Expected '%t', got '%t' instead\n", tc.expected, f)
+			}
+		})
+	}
+}
+
+func TestFilterOutExpressions(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "access-2024.log")
+	if err := os.WriteFile(fpath, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	modified := time.Now().Add(-10 * 24 * time.Hour)
+	if err := os.Chtimes(fpath, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := 24 * time.Hour
+
+	testsCases := []struct {
+		name     string
+		cfg      config
+		expected bool
+	}{
+		{"FilterExtensionsMatch", config{exts: []string{".txt", ".log"}}, false},
+		{"FilterExtensionsNoMatch", config{exts: []string{".txt", ".sh"}}, true},
+
+		{"FilterGlobMatch", config{globs: []string{"*.txt", "access-*.log"}}, false},
+		{"FilterGlobNoMatch", config{globs: []string{"error-*"}}, true},
+
+		{"FilterRegexMatch", config{regexps: []*regexp.Regexp{regexp.MustCompile(`-\d{4}\.log$`)}}, false},
+		{"FilterRegexNoMatch", config{regexps: []*regexp.Regexp{regexp.MustCompile(`^error`)}}, true},
+
+		{"FilterSizeRangeMatch", config{size: 5, maxSize: 10}, false},
+		{"FilterMaxSizeNoMatch", config{maxSize: 9}, true},
+
+		{"FilterOlderMatch", config{older: 7 * day}, false},
+		{"FilterOlderNoMatch", config{older: 30 * day}, true},
+		{"FilterNewerMatch", config{newer: 30 * day}, false},
+		{"FilterNewerNoMatch", config{newer: 7 * day}, true},
+
+		{"FilterCombinedNoMatch", config{exts: []string{".log"}, older: 30 * day}, true},
+	}
+
+	for _, tc := range testsCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := filterOut(fpath, info, tc.cfg)
 
 			if f != tc.expected {
 				t.Errorf("Expected '%t', got '%t' instead\n", tc.expected, f)
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrArchiveFormat = errors.New("Unsupported archive format, use tar.gz or zip")
+	ErrNoArchive     = errors.New("Archive format given without an archive file")
+	ErrVerify        = errors.New("Archive does not match its manifest")
+)
+
+// manifestName is the entry listing the SHA-256 checksum of every other
+// entry of a bundle, in the format of sha256sum.
+const manifestName = "MANIFEST.sha256"
+
+// bundle is a single archive collecting every matched file.
+type bundle interface {
+	// add writes the file at path to the archive under the slash separated
+	// name, keeping its mode and modification time, and returns its
+	// checksum.
+	add(path, name string, info fs.FileInfo) ([]byte, error)
+	addBytes(name string, data []byte) error
+	Close() error
+}
+
+// manifestBundle records the checksum of every file added to a bundle and
+// writes the manifest when it is closed.
+type manifestBundle struct {
+	bundle
+	manifest bytes.Buffer
+}
+
+func (b *manifestBundle) add(path, name string, info fs.FileInfo) ([]byte, error) {
+	sum, err := b.bundle.add(path, name, info)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&b.manifest, "%x  %s\n", sum, name)
+	return sum, nil
+}
+
+func (b *manifestBundle) Close() error {
+	if err := b.bundle.addBytes(manifestName, b.manifest.Bytes()); err != nil {
+		b.bundle.Close()
+		return err
+	}
+
+	return b.bundle.Close()
+}
+
+// checkBundle makes sure the bundle cfg asks for can be written, before
+// the walk starts.
+func checkBundle(cfg config) error {
+	switch {
+	case cfg.archiveFormat == "":
+		return nil
+	case cfg.archive == "":
+		return ErrNoArchive
+	case cfg.archiveFormat != "tar.gz" && cfg.archiveFormat != "zip":
+		return fmt.Errorf("%w: %q", ErrArchiveFormat, cfg.archiveFormat)
+	}
+
+	return nil
+}
+
+// newBundle creates the archive fname in format, tar.gz or zip, as checked
+// by checkBundle.
+func newBundle(fname, format string) (bundle, error) {
+	f, err := os.Create(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "zip" {
+		return &manifestBundle{bundle: &zipBundle{f: f, zw: zip.NewWriter(f)}}, nil
+	}
+
+	gw := gzip.NewWriter(f)
+	return &manifestBundle{bundle: &tarBundle{f: f, gw: gw, tw: tar.NewWriter(gw)}}, nil
+}
+
+type tarBundle struct {
+	f  *os.File
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+func (b *tarBundle) add(path, name string, info fs.FileInfo) ([]byte, error) {
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return nil, err
+	}
+	hdr.Name = name
+
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+
+	return copyHashed(b.tw, path)
+}
+
+func (b *tarBundle) addBytes(name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	_, err := b.tw.Write(data)
+	return err
+}
+
+func (b *tarBundle) Close() error {
+	if err := b.tw.Close(); err != nil {
+		b.f.Close()
+		return err
+	}
+
+	if err := b.gw.Close(); err != nil {
+		b.f.Close()
+		return err
+	}
+
+	return b.f.Close()
+}
+
+type zipBundle struct {
+	f  *os.File
+	zw *zip.Writer
+}
+
+func (b *zipBundle) add(path, name string, info fs.FileInfo) ([]byte, error) {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return nil, err
+	}
+	hdr.Name = name
+	hdr.Method = zip.Deflate
+
+	w, err := b.zw.CreateHeader(hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyHashed(w, path)
+}
+
+func (b *zipBundle) addBytes(name string, data []byte) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()}
+	hdr.SetMode(0644)
+
+	w, err := b.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func (b *zipBundle) Close() error {
+	if err := b.zw.Close(); err != nil {
+		b.f.Close()
+		return err
+	}
+
+	return b.f.Close()
+}
+
+// copyHashed copies the file at path to w, returning its SHA-256 checksum.
+func copyHashed(w io.Writer, path string) ([]byte, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	h := sha256.New()
+
+	if _, err := io.Copy(io.MultiWriter(w, h), in); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+// bundleFile adds the file at path to b, named after its path relative to
+// root.
+func bundleFile(b bundle, root, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.add(path, filepath.ToSlash(rel), info)
+	return err
+}
+
+// archiveFormat returns the format of the archive fname from its extension.
+func archiveFormat(fname string) (string, error) {
+	switch {
+	case strings.HasSuffix(fname, ".tar.gz"), strings.HasSuffix(fname, ".tgz"):
+		return "tar.gz", nil
+	case strings.HasSuffix(fname, ".zip"):
+		return "zip", nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrArchiveFormat, fname)
+}
+
+// verify checks every entry of the bundle fname against its manifest,
+// printing a line per entry like sha256sum -c does.
+func verify(fname string, out io.Writer) error {
+	format, err := archiveFormat(fname)
+	if err != nil {
+		return err
+	}
+
+	sums := map[string]string{}
+	var manifest []byte
+
+	collect := func(name string, r io.Reader) error {
+		if name == manifestName {
+			manifest, err = io.ReadAll(r)
+			return err
+		}
+
+		h := sha256.New()
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	}
+
+	if format == "zip" {
+		err = walkZip(fname, collect)
+	} else {
+		err = walkTarGz(fname, collect)
+	}
+	if err != nil {
+		return err
+	}
+
+	if manifest == nil {
+		return fmt.Errorf("%w: no %s", ErrVerify, manifestName)
+	}
+
+	failed := false
+	listed := map[string]bool{}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(manifest)), "\n") {
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			continue
+		}
+		listed[name] = true
+
+		status := "OK"
+		switch got, found := sums[name]; {
+		case !found:
+			status = "MISSING"
+		case got != sum:
+			status = "FAILED"
+		}
+
+		if status != "OK" {
+			failed = true
+		}
+
+		fmt.Fprintf(out, "%s: %s\n", name, status)
+	}
+
+	extra := []string{}
+	for name := range sums {
+		if !listed[name] {
+			extra = append(extra, name)
+		}
+	}
+	slices.Sort(extra)
+
+	for _, name := range extra {
+		fmt.Fprintf(out, "%s: NOT IN MANIFEST\n", name)
+		failed = true
+	}
+
+	if failed {
+		return ErrVerify
+	}
+
+	return nil
+}
+
+func walkTarGz(fname string, fn func(name string, r io.Reader) error) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := fn(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(fname string, fn func(name string, r io.Reader) error) error {
+	zr, err := zip.OpenReader(fname)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+
+		r, err := zf.Open()
+		if err != nil {
+			return err
+		}
+
+		err = fn(zf.Name, r)
+		r.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type entry struct {
+	mode    fs.FileMode
+	modTime time.Time
+	data    string
+}
+
+func readBundle(t *testing.T, fname, format string) map[string]entry {
+	t.Helper()
+
+	entries := map[string]entry{}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if format == "zip" {
+		info, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, zf := range zr.File {
+			r, err := zf.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			buf.ReadFrom(r)
+			r.Close()
+
+			entries[zf.Name] = entry{zf.Mode().Perm(), zf.Modified, buf.String()}
+		}
+
+		return entries
+	}
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+
+		var buf bytes.Buffer
+		buf.ReadFrom(tr)
+
+		entries[hdr.Name] = entry{fs.FileMode(hdr.Mode).Perm(), hdr.ModTime, buf.String()}
+	}
+
+	return entries
+}
+
+func TestRunArchiveBundle(t *testing.T) {
+	modTime := time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC)
+
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			root := t.TempDir()
+
+			files := map[string]fs.FileMode{
+				"app.log":          0644,
+				"jobs/run.log":     0755,
+				"jobs/old/1.log":   0600,
+				"jobs/notes.txt":   0644,
+				"bundle." + format: 0644,
+			}
+
+			for name, mode := range files {
+				fpath := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(fpath, []byte(name), mode); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.Chmod(fpath, mode); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.Chtimes(fpath, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			bundleName := filepath.Join(root, "bundle."+format)
+
+			var buffer bytes.Buffer
+
+			cfg := config{
+				exts:          []string{".log", filepath.Ext(bundleName)},
+				archive:       bundleName,
+				archiveFormat: format,
+			}
+
+			if err := run(root, &buffer, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			entries := readBundle(t, bundleName, format)
+
+			for _, name := range []string{"app.log", "jobs/run.log", "jobs/old/1.log"} {
+				e, ok := entries[name]
+				if !ok {
+					t.Errorf("Expected %s in the archive\n", name)
+					continue
+				}
+
+				if e.data != name {
+					t.Errorf("Expected %s to contain %q, got %q instead\n", name, name, e.data)
+				}
+
+				if e.mode != files[name] {
+					t.Errorf("Expected %s mode %s, got %s instead\n", name, files[name], e.mode)
+				}
+
+				if !e.modTime.Equal(modTime) {
+					t.Errorf("Expected %s mtime %s, got %s instead\n", name, modTime, e.modTime)
+				}
+			}
+
+			if len(entries) != 4 {
+				t.Errorf("Expected 3 files and the manifest, got %d entries instead\n", len(entries))
+			}
+
+			if !strings.Contains(entries[manifestName].data, "  jobs/old/1.log\n") {
+				t.Errorf("Expected the manifest to list the files, got %q instead\n", entries[manifestName].data)
+			}
+
+			var out bytes.Buffer
+			if err := verify(bundleName, &out); err != nil {
+				t.Fatal(err, out.String())
+			}
+
+			if n := strings.Count(out.String(), ": OK\n"); n != 3 {
+				t.Errorf("Expected 3 files verified, got %q instead\n", out.String())
+			}
+		})
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			bundleName := filepath.Join(t.TempDir(), "bundle."+format)
+
+			b, err := newBundle(bundleName, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			raw := b.(*manifestBundle).bundle
+
+			manifest := "0000  changed.txt\n" +
+				"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  missing.txt\n"
+
+			for _, e := range []struct{ name, data string }{
+				{"changed.txt", "hello"},
+				{"extra.txt", "extra"},
+				{manifestName, manifest},
+			} {
+				if err := raw.addBytes(e.name, []byte(e.data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := raw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := verify(bundleName, &out); !errors.Is(err, ErrVerify) {
+				t.Fatalf("Expected error %v, got %v instead\n", ErrVerify, err)
+			}
+
+			expected := "changed.txt: FAILED\nmissing.txt: MISSING\nextra.txt: NOT IN MANIFEST\n"
+			if out.String() != expected {
+				t.Errorf("Expected %q, got %q instead\n", expected, out.String())
+			}
+		})
+	}
+}
+
+func TestRunArchiveBundleInvalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		cfg    config
+		expErr error
+	}{
+		{name: "NoArchive", cfg: config{archiveFormat: "zip"}, expErr: ErrNoArchive},
+		{name: "BadFormat", cfg: config{archive: "bundle.rar", archiveFormat: "rar"}, expErr: ErrArchiveFormat},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir, cleanup := createTempDir(t, map[string]int{".log": 2})
+			defer cleanup()
+
+			var buffer bytes.Buffer
+
+			cfg := tc.cfg
+			cfg.exts = []string{".log"}
+			cfg.delete = true
+
+			if err := run(tempDir, &buffer, cfg); !errors.Is(err, tc.expErr) {
+				t.Fatalf("Expected error %v, got %v instead\n", tc.expErr, err)
+			}
+
+			if filesLeft, _ := os.ReadDir(tempDir); len(filesLeft) != 2 {
+				t.Errorf("Expected 2 files kept, got %d instead\n", len(filesLeft))
+			}
+		})
+	}
+}
+
+func TestRunArchiveBundleCloseFails(t *testing.T) {
+	// Writes to /dev/full fail with ENOSPC, once the archive is flushed on
+	// close for files this small.
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full to fill up")
+	}
+
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 3})
+	defer cleanup()
+
+	var buffer, logBuffer bytes.Buffer
+
+	cfg := config{
+		exts:          []string{".log"},
+		archive:       "/dev/full",
+		archiveFormat: "zip",
+		delete:        true,
+		jobs:          2,
+		wLog:          &logBuffer,
+	}
+
+	if err := run(tempDir, &buffer, cfg); err == nil {
+		t.Fatal("Expected an error closing the archive, got nil instead")
+	}
+
+	filesLeft, err := filepath.Glob(filepath.Join(tempDir, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(filesLeft) != 3 {
+		t.Errorf("Expected every file kept when the archive fails, got %d left instead\n", len(filesLeft))
+	}
+
+	if logBuffer.Len() != 0 {
+		t.Errorf("Expected nothing logged, got %q instead\n", logBuffer.String())
+	}
+}
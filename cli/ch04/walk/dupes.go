@@ -0,0 +1,311 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+var ErrInvalidKeep = errors.New("Keep must be oldest or newest")
+
+// partialSize is how much of each file is hashed before hashing all of it,
+// which rules out most files of the same size cheaply.
+const partialSize = 4 << 10
+
+// dupe is a file with the same content as others.
+type dupe struct {
+	match
+	info fs.FileInfo
+}
+
+// findDupes groups the files with the same content, narrowing them down by
+// size, then by a hash of their first bytes and then by a hash of all of
+// them. Empty files and hard links to a file already seen are ignored.
+// Groups are in walk order, with the file to keep, the oldest or the
+// newest, first.
+func findDupes(matches []match, keep string, jobs int) ([][]dupe, []error) {
+	errs := []error{}
+
+	bySize := map[int64][]dupe{}
+	for _, m := range matches {
+		if m.size == 0 {
+			continue
+		}
+
+		info, err := os.Stat(m.path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		same := slices.ContainsFunc(bySize[m.size], func(d dupe) bool {
+			return os.SameFile(d.info, info)
+		})
+		if !same {
+			bySize[m.size] = append(bySize[m.size], dupe{m, info})
+		}
+	}
+
+	groups := [][]dupe{}
+	for _, g := range bySize {
+		if len(g) > 1 {
+			groups = append(groups, g)
+		}
+	}
+
+	groups, hashErrs := splitByHash(groups, partialSize, jobs)
+	errs = append(errs, hashErrs...)
+
+	// Files that fit in the partial hash were hashed in full already.
+	small := [][]dupe{}
+	large := [][]dupe{}
+	for _, g := range groups {
+		if g[0].size <= partialSize {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+
+	large, hashErrs = splitByHash(large, -1, jobs)
+	errs = append(errs, hashErrs...)
+
+	groups = append(small, large...)
+
+	for _, g := range groups {
+		slices.SortFunc(g, func(a, b dupe) int {
+			c := a.info.ModTime().Compare(b.info.ModTime())
+			if keep == "newest" {
+				c = -c
+			}
+			if c == 0 {
+				c = a.idx - b.idx
+			}
+
+			return c
+		})
+	}
+
+	slices.SortFunc(groups, func(a, b []dupe) int {
+		return minIdx(a) - minIdx(b)
+	})
+
+	return groups, errs
+}
+
+func minIdx(g []dupe) int {
+	idx := g[0].idx
+	for _, d := range g[1:] {
+		idx = min(idx, d.idx)
+	}
+
+	return idx
+}
+
+// splitByHash splits every group by the SHA-256 hash of the first limit
+// bytes of its files, or all of them when limit is negative, keeping the
+// groups of more than one file.
+func splitByHash(groups [][]dupe, limit int64, jobs int) ([][]dupe, []error) {
+	files := []dupe{}
+	for _, g := range groups {
+		files = append(files, g...)
+	}
+
+	sums, errs := hashFiles(files, limit, jobs)
+
+	split := [][]dupe{}
+	for _, g := range groups {
+		byHash := map[string][]dupe{}
+		order := []string{}
+
+		for _, d := range g {
+			sum, ok := sums[d.path]
+			if !ok {
+				continue
+			}
+
+			if _, seen := byHash[sum]; !seen {
+				order = append(order, sum)
+			}
+			byHash[sum] = append(byHash[sum], d)
+		}
+
+		for _, sum := range order {
+			if len(byHash[sum]) > 1 {
+				split = append(split, byHash[sum])
+			}
+		}
+	}
+
+	return split, errs
+}
+
+// hashFiles hashes the files with a pool of jobs workers.
+func hashFiles(files []dupe, limit int64, jobs int) (map[string]string, []error) {
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		sums = map[string]string{}
+		errs = []error{}
+		sem  = make(chan struct{}, jobs)
+	)
+
+	for _, d := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			sum, err := hashFile(d.path, limit)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+
+			sums[d.path] = sum
+		}()
+	}
+
+	wg.Wait()
+
+	return sums, errs
+}
+
+func hashFile(path string, limit int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dupes prints the groups of duplicate files among matches, separated by
+// blank lines and with the copy to keep first. With -del or -link it
+// then deletes, trashes or hard links every other copy, logging each.
+func dupes(matches []match, out io.Writer, cfg config) error {
+	keep := cfg.keep
+	if keep == "" {
+		keep = "oldest"
+	}
+
+	if keep != "oldest" && keep != "newest" {
+		return fmt.Errorf("%w: %q", ErrInvalidKeep, keep)
+	}
+
+	groups, errs := findDupes(matches, keep, cfg.jobs)
+
+	extra := []match{}
+
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+
+		for j, d := range g {
+			fmt.Fprintln(out, d.path)
+
+			if j > 0 {
+				extra = append(extra, d.match)
+			}
+		}
+	}
+
+	if !cfg.delete && !cfg.link || len(extra) == 0 {
+		return errors.Join(errs...)
+	}
+
+	fmt.Fprintln(out)
+
+	if cfg.dryRun {
+		return errors.Join(append(errs, dryRun(extra, out, cfg))...)
+	}
+
+	if cfg.confirm {
+		ok, err := confirm(cfg.in, out, fmt.Sprintf("%s %s?", capitalize(action(cfg)), summary(extra)))
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		if !ok {
+			return errors.Join(append(errs, ErrAborted)...)
+		}
+	}
+
+	deleteLogger := log.New(cfg.wLog, "DELETED FILE: ", log.LstdFlags)
+	trashLogger := log.New(cfg.wLog, trashLogPrefix, log.LstdFlags)
+	linkLogger := log.New(cfg.wLog, "LINKED FILE: ", log.LstdFlags)
+
+	for _, g := range groups {
+		kept := g[0].path
+
+		for _, d := range g[1:] {
+			var err error
+
+			switch {
+			case cfg.link:
+				err = linkFile(d.path, kept, linkLogger)
+			case cfg.trash != "":
+				err = trashFile(d.path, cfg.trash, trashLogger)
+			default:
+				err = deleteFile(d.path, deleteLogger)
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// linkFile replaces path with a hard link to target. The link is made next
+// to path first and renamed over it, so path is never missing.
+func linkFile(path, target string, linkLogger *log.Logger) error {
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.walk-link", filepath.Base(path)))
+
+	if err := os.Link(target, tmp); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	linkLogger.Println(path + logArrow + target)
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeDupes writes every file under root, setting the modification times
+// one hour apart in the order given.
+func writeDupes(t *testing.T, root string, files []struct{ name, data string }) {
+	t.Helper()
+
+	modTime := time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC)
+
+	for i, f := range files {
+		fpath := filepath.Join(root, f.name)
+		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(fpath, []byte(f.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		mtime := modTime.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(fpath, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunDupes(t *testing.T) {
+	head := strings.Repeat("x", partialSize)
+
+	testCases := []struct {
+		name     string
+		keep     string
+		exts     []string
+		expected string
+	}{
+		{name: "Oldest", keep: "oldest",
+			expected: "a/one.txt\nb/one.txt\n\nbig1.bin\nbig3.bin\n\ncopy.log\norig.log\n"},
+		{name: "Newest", keep: "newest",
+			expected: "b/one.txt\na/one.txt\n\nbig3.bin\nbig1.bin\n\norig.log\ncopy.log\n"},
+		{name: "FilterExt", keep: "oldest", exts: []string{".txt"},
+			expected: "a/one.txt\nb/one.txt\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			writeDupes(t, root, []struct{ name, data string }{
+				{"a/one.txt", "same"},
+				{"big1.bin", head + "tail"},
+				{"copy.log", "log data"},
+				{"b/one.txt", "same"},
+				{"big2.bin", head + "diff"},
+				{"big3.bin", head + "tail"},
+				{"orig.log", "log data"},
+				{"other.txt", "diff"},
+				{"empty1.txt", ""},
+				{"empty2.txt", ""},
+			})
+
+			var buffer bytes.Buffer
+
+			cfg := config{dupes: true, keep: tc.keep, exts: tc.exts}
+
+			if err := run(root, &buffer, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			res := strings.ReplaceAll(buffer.String(), root+string(filepath.Separator), "")
+			res = filepath.ToSlash(res)
+
+			if res != tc.expected {
+				t.Errorf("Expected %q, got %q instead\n", tc.expected, res)
+			}
+		})
+	}
+}
+
+func TestRunDupesInvalidKeep(t *testing.T) {
+	var buffer bytes.Buffer
+
+	err := run(t.TempDir(), &buffer, config{dupes: true, keep: "largest"})
+	if !errors.Is(err, ErrInvalidKeep) {
+		t.Errorf("Expected error %v, got %v instead\n", ErrInvalidKeep, err)
+	}
+}
+
+func TestRunDupesAction(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cfg       config
+		expLeft   []string
+		expLogged int
+		expPrefix string
+	}{
+		{name: "Delete", cfg: config{delete: true},
+			expLeft: []string{"file1.log"}, expLogged: 2, expPrefix: "DELETED FILE: "},
+		{name: "Link", cfg: config{link: true},
+			expLeft: []string{"file1.log", "file2.log", "file3.log"}, expLogged: 2, expPrefix: "LINKED FILE: "},
+		{name: "DryRun", cfg: config{delete: true, dryRun: true},
+			expLeft: []string{"file1.log", "file2.log", "file3.log"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			writeDupes(t, root, []struct{ name, data string }{
+				{"file1.log", "dummy"},
+				{"file2.log", "dummy"},
+				{"file3.log", "dummy"},
+			})
+
+			var buffer, logBuffer bytes.Buffer
+
+			cfg := tc.cfg
+			cfg.dupes = true
+			cfg.wLog = &logBuffer
+
+			if err := run(root, &buffer, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, name := range tc.expLeft {
+				if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+					t.Errorf("Expected %s to be kept, got %v instead\n", name, err)
+				}
+			}
+
+			left, err := filepath.Glob(filepath.Join(root, "*"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(left) != len(tc.expLeft) {
+				t.Errorf("Expected %d files left, got %d instead\n", len(tc.expLeft), len(left))
+			}
+
+			if n := strings.Count(logBuffer.String(), tc.expPrefix); tc.expPrefix != "" && n != tc.expLogged {
+				t.Errorf("Expected %d lines logged, got %q instead\n", tc.expLogged, logBuffer.String())
+			}
+
+			if tc.cfg.dryRun && logBuffer.Len() != 0 {
+				t.Errorf("Expected nothing logged, got %q instead\n", logBuffer.String())
+			}
+
+			if !tc.cfg.link {
+				return
+			}
+
+			kept, err := os.Stat(filepath.Join(root, "file1.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, name := range []string{"file2.log", "file3.log"} {
+				info, err := os.Stat(filepath.Join(root, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !os.SameFile(kept, info) {
+					t.Errorf("Expected %s to be linked to file1.log\n", name)
+				}
+			}
+		})
+	}
+}
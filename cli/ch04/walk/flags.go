@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSize = errors.New("Invalid size")
+	ErrInvalidAge  = errors.New("Invalid age")
+)
+
+// stringList is a flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// regexpList is a flag of regular expressions that can be given more than
+// once.
+type regexpList []*regexp.Regexp
+
+func (l *regexpList) String() string {
+	patterns := []string{}
+	for _, re := range *l {
+		patterns = append(patterns, re.String())
+	}
+
+	return strings.Join(patterns, ",")
+}
+
+func (l *regexpList) Set(value string) error {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return err
+	}
+
+	*l = append(*l, re)
+	return nil
+}
+
+// byteSize is a size flag in bytes accepting K, M and G suffixes.
+type byteSize int64
+
+func (s *byteSize) String() string {
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+func (s *byteSize) Set(value string) error {
+	units := map[string]int64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30}
+
+	n, multiplier := value, int64(1)
+	for suffix, unit := range units {
+		if trimmed, ok := strings.CutSuffix(strings.ToUpper(value), suffix); ok {
+			n, multiplier = trimmed, unit
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(n, 10, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidSize, value)
+	}
+
+	*s = byteSize(size * multiplier)
+	return nil
+}
+
+// age is a duration flag that also accepts days and weeks, like 30d or 2w.
+type age time.Duration
+
+func (a *age) String() string {
+	return time.Duration(*a).String()
+}
+
+func (a *age) Set(value string) error {
+	d, err := parseAge(value)
+	if err != nil {
+		return err
+	}
+
+	*a = age(d)
+	return nil
+}
+
+func parseAge(value string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+
+	for suffix, unit := range units {
+		n, ok := strings.CutSuffix(value, suffix)
+		if !ok {
+			continue
+		}
+
+		days, err := strconv.Atoi(n)
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAge, value)
+		}
+
+		return time.Duration(days) * unit, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAge, value)
+	}
+
+	return d, nil
+}
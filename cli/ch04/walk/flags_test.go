@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestByteSize(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected int64
+		expErr   error
+	}{
+		{"500", 500, nil},
+		{"10K", 10 << 10, nil},
+		{"2m", 2 << 20, nil},
+		{"1G", 1 << 30, nil},
+		{"", 0, ErrInvalidSize},
+		{"-1", 0, ErrInvalidSize},
+		{"1.5M", 0, ErrInvalidSize},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			var s byteSize
+
+			err := s.Set(tc.value)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("Expected error %v, got %v instead\n", tc.expErr, err)
+			}
+
+			if int64(s) != tc.expected {
+				t.Errorf("Expected %d, got %d instead\n", tc.expected, s)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		expErr   error
+	}{
+		{"30d", 30 * 24 * time.Hour, nil},
+		{"2w", 14 * 24 * time.Hour, nil},
+		{"12h", 12 * time.Hour, nil},
+		{"1h30m", 90 * time.Minute, nil},
+		{"d", 0, ErrInvalidAge},
+		{"-3d", 0, ErrInvalidAge},
+		{"soon", 0, ErrInvalidAge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			d, err := parseAge(tc.value)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("Expected error %v, got %v instead\n", tc.expErr, err)
+			}
+
+			if d != tc.expected {
+				t.Errorf("Expected %s, got %s instead\n", tc.expected, d)
+			}
+		})
+	}
+}
@@ -1,41 +1,108 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
-	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
 type config struct {
-	name   string
-	ext    string
-	size   int64
+	name     string
+	exts     []string
+	globs    []string
+	regexps  []*regexp.Regexp
+	size     int64
+	maxSize  int64
+	older    time.Duration
+	newer    time.Duration
+	excludes []string
+
 	list   bool
 	delete bool
+	dryRun bool
+	// dupes finds files with the same content, deleting or linking all
+	// but the one to keep, the oldest or newest, with delete or link.
+	dupes bool
+	keep  string
+	link  bool
+	// confirm asks before deleting, reading the answer from in.
+	confirm bool
+	in      io.Reader
+	// trash moves deleted files to this directory instead of removing them.
+	trash string
 
-	wLog    io.Writer
+	wLog io.Writer
+	// logName is the file wLog writes to, if any, kept out of the walk.
+	logName string
 	archive string
+	// archiveFormat bundles the files into the single archive named by
+	// archive instead of compressing them one by one into that directory.
+	archiveFormat string
+	// jobs is the number of files processed at once, all CPUs when zero.
+	jobs int
 }
 
+var ErrAborted = errors.New("Aborted")
+
 func main() {
-	c := config{}
+	switch {
+	case len(os.Args) > 1 && os.Args[1] == "restore":
+		if err := restoreCmd(os.Args[2:], os.Stdout, os.Stderr); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+
+	case len(os.Args) > 1 && os.Args[1] == "verify":
+		if err := verifyCmd(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	c := config{in: os.Stdin}
 
 	root := flag.String("root", ".", "Root directory to start")
 	logFile := flag.String("log", "", "Log deletes to this file")
 
-	flag.StringVar(&c.archive, "archive", "", "Archive directory")
+	flag.StringVar(&c.archive, "archive", "", "Archive directory, or archive file with -archive-format")
+	flag.StringVar(&c.archiveFormat, "archive-format", "", "Bundle the files into one tar.gz or zip archive with a SHA-256 manifest")
 	flag.StringVar(&c.name, "name", "", "File name to filter out")
-	flag.StringVar(&c.ext, "ext", "", "File extension to filter out")
-	flag.Int64Var(&c.size, "size", 0, "Minimum file size")
+	flag.Var((*stringList)(&c.exts), "ext", "File extension to filter out, can be repeated")
+	flag.Var((*stringList)(&c.globs), "glob", "File name glob to filter out, like '*.log', can be repeated")
+	flag.Var((*regexpList)(&c.regexps), "regex", "File name regular expression to filter out, can be repeated")
+	flag.Var((*byteSize)(&c.size), "size", "Minimum file size, like 500 or 10K")
+	flag.Var((*byteSize)(&c.maxSize), "max-size", "Maximum file size, like 10M")
+	flag.Var((*age)(&c.older), "older", "Only files modified longer ago than this, like 30d, 2w or 12h")
+	flag.Var((*age)(&c.newer), "newer", "Only files modified more recently than this")
+	flag.Var((*stringList)(&c.excludes), "exclude", "Directory name glob to skip, like .git or node_modules, can be repeated")
 
 	flag.BoolVar(&c.list, "list", false, "List files only")
 	flag.BoolVar(&c.delete, "del", false, "Delete files")
+	flag.BoolVar(&c.dryRun, "dry-run", false, "Print what would be done and how much space freed, without changing anything")
+	flag.StringVar(&c.trash, "trash", "", "Move deleted files to this trash directory, to bring them back with walk restore")
+	flag.BoolVar(&c.dupes, "dupes", false, "Find duplicate files, printing them in groups")
+	flag.StringVar(&c.keep, "keep", "oldest", "Duplicate to keep with -del or -link, oldest or newest")
+	flag.BoolVar(&c.link, "link", false, "Replace duplicates with hard links to the one kept")
+	yes := flag.Bool("yes", false, "Delete without asking for confirmation")
+	flag.IntVar(&c.jobs, "jobs", runtime.NumCPU(), "Number of files to archive and delete at once")
 
 	flag.Parse()
 
+	c.confirm = (c.delete || c.link) && !*yes
+	c.logName = *logFile
+
 	var (
 		f   = os.Stdout
 		err error
@@ -55,31 +122,278 @@ func main() {
 }
 
 func run(root string, out io.Writer, cfg config) error {
-	deleteLogger := log.New(cfg.wLog, "DELETED FILE: ", log.LstdFlags)
+	if err := checkBundle(cfg); err != nil {
+		return err
+	}
 
-	return filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	walkErrs := []error{}
+	matches := walk(root, cfg, &walkErrs)
 
-		if filterOut(path, cfg.ext, cfg.name, cfg.size, info) {
-			return nil
-		}
+	var err error
+
+	switch {
+	case cfg.dupes:
+		err = dupes(collect(matches), out, cfg)
+	case cfg.list:
+		err = listAll(matches, out)
+	case cfg.dryRun:
+		err = dryRun(collect(matches), out, cfg)
+	default:
+		err = apply(matches, root, out, cfg)
+	}
+
+	// Waits for the walk to end, if an error stopped reading it early,
+	// before looking at its errors.
+	for range matches {
+	}
+
+	if len(walkErrs) == 0 {
+		return err
+	}
 
-		if cfg.list {
-			return listFile(path, out)
+	return errors.Join(append(walkErrs, err)...)
+}
+
+// listAll prints every matched file.
+func listAll(matches <-chan match, out io.Writer) error {
+	errs := []error{}
+
+	for m := range matches {
+		if err := listFile(m.path, out); err != nil {
+			errs = append(errs, err)
 		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// apply archives and deletes the matched files in parallel, asking first
+// when cfg says so.
+func apply(matches <-chan match, root string, out io.Writer, cfg config) error {
+	if cfg.delete && cfg.confirm {
+		all := collect(matches)
 
-		if cfg.archive != "" {
-			if err := archiveFile(cfg.archive, root, path); err != nil {
+		if len(all) > 0 {
+			ok, err := confirm(cfg.in, out, fmt.Sprintf("%s %s?", capitalize(action(cfg)), summary(all)))
+			if err != nil {
 				return err
 			}
+
+			if !ok {
+				return ErrAborted
+			}
 		}
 
-		if cfg.delete {
-			return deleteFile(path, deleteLogger)
+		matches = feed(all)
+	}
+
+	if cfg.archive != "" && cfg.archiveFormat != "" {
+		return applyBundle(matches, root, out, cfg)
+	}
+
+	return runPool(matches, cfg.jobs, out, cfg.wLog, func(m match, r *result) error {
+		return process(m, root, r, cfg)
+	})
+}
+
+// applyBundle adds the matched files to the archive named by cfg and only
+// deletes them once it is closed, as the archive cannot be read before.
+// If closing it fails, every file is kept.
+func applyBundle(matches <-chan match, root string, out io.Writer, cfg config) error {
+	nb, err := newBundle(cfg.archive, cfg.archiveFormat)
+	if err != nil {
+		return err
+	}
+
+	b := &lockedBundle{bundle: nb}
+
+	var (
+		mu      sync.Mutex
+		bundled = []match{}
+	)
+
+	err = runPool(matches, cfg.jobs, out, cfg.wLog, func(m match, r *result) error {
+		if err := bundleFile(b, root, m.path); err != nil {
+			return err
+		}
+
+		if !cfg.delete {
+			return listFile(m.path, &r.out)
 		}
 
-		return listFile(path, out)
+		mu.Lock()
+		defer mu.Unlock()
+
+		bundled = append(bundled, m)
+		return nil
+	})
+
+	if closeErr := b.Close(); closeErr != nil {
+		return errors.Join(err, closeErr)
+	}
+
+	if len(bundled) == 0 {
+		return err
+	}
+
+	// Numbers the files again, in walk order, for the pool to log their
+	// deletes in that order.
+	slices.SortFunc(bundled, func(a, b match) int {
+		return a.idx - b.idx
 	})
+	for i := range bundled {
+		bundled[i].idx = i
+	}
+
+	return errors.Join(err, runPool(feed(bundled), cfg.jobs, out, cfg.wLog, func(m match, r *result) error {
+		return remove(m, r, cfg)
+	}))
+}
+
+// process archives, lists and deletes the matched file as cfg says. Output
+// and log lines go to r.
+func process(m match, root string, r *result, cfg config) error {
+	if cfg.archive != "" {
+		if err := archiveFile(cfg.archive, root, m.path); err != nil {
+			return err
+		}
+	}
+
+	if !cfg.delete {
+		return listFile(m.path, &r.out)
+	}
+
+	return remove(m, r, cfg)
+}
+
+// remove deletes the matched file, or moves it to the trash when cfg has
+// one, logging it to r.
+func remove(m match, r *result, cfg config) error {
+	if cfg.trash != "" {
+		return trashFile(m.path, cfg.trash, log.New(&r.log, trashLogPrefix, log.LstdFlags))
+	}
+
+	return deleteFile(m.path, log.New(&r.log, "DELETED FILE: ", log.LstdFlags))
+}
+
+// dryRun prints the actions run would take on every file, and how many
+// files and bytes they add up to.
+func dryRun(matches []match, out io.Writer, cfg config) error {
+	act := action(cfg)
+
+	for _, m := range matches {
+		if _, err := fmt.Fprintf(out, "would %s %s (%d bytes)\n", act, m.path, m.size); err != nil {
+			return err
+		}
+	}
+
+	freed := ""
+	if cfg.delete && cfg.trash == "" {
+		freed = ", freeing space"
+	}
+
+	_, err := fmt.Fprintf(out, "would %s %s%s\n", act, summary(matches), freed)
+	return err
+}
+
+func action(cfg config) string {
+	switch {
+	case cfg.dupes && cfg.link:
+		return "hard link"
+	case cfg.archive != "" && cfg.delete && cfg.trash != "":
+		return "archive and trash"
+	case cfg.archive != "" && cfg.delete:
+		return "archive and delete"
+	case cfg.archive != "":
+		return "archive"
+	case cfg.delete && cfg.trash != "":
+		return "trash"
+	case cfg.delete:
+		return "delete"
+	}
+
+	return "list"
+}
+
+func summary(matches []match) string {
+	var total int64
+	for _, m := range matches {
+		total += m.size
+	}
+
+	return fmt.Sprintf("%d files (%d bytes)", len(matches), total)
+}
+
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// confirm asks question on out and reports whether the answer read from in
+// is yes.
+func confirm(in io.Reader, out io.Writer, question string) (bool, error) {
+	if _, err := fmt.Fprintf(out, "%s [y/N] ", question); err != nil {
+		return false, err
+	}
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// restoreCmd puts the files trashed by walk back where they were, reading
+// the moves from the delete log.
+func restoreCmd(args []string, out, errOut io.Writer) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	logFile := flags.String("log", "", "Delete log written with -trash")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *logFile == "" {
+		return fmt.Errorf("%w: -log is required", ErrRestore)
+	}
+
+	f, err := os.Open(*logFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return restore(f, out, errOut)
+}
+
+// verifyCmd checks the archives created with -archive-format against their
+// manifests.
+func verifyCmd(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: no archive given", ErrVerify)
+	}
+
+	failed := false
+
+	for _, fname := range args {
+		err := verify(fname, out)
+		if errors.Is(err, ErrVerify) {
+			failed = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return ErrVerify
+	}
+
+	return nil
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,7 +22,6 @@ func TestRun(t *testing.T) {
 			name: "NoFilter",
 			root: "testdata",
 			cfg: config{
-				ext:  "",
 				size: 0,
 				list: true,
 			},
@@ -30,7 +31,7 @@ func TestRun(t *testing.T) {
 			name: "FilterExtensionMatch",
 			root: "testdata",
 			cfg: config{
-				ext:  ".log",
+				exts: []string{".log"},
 				size: 0,
 				list: true,
 			},
@@ -40,7 +41,7 @@ func TestRun(t *testing.T) {
 			name: "FilterExtensionSizeMatch",
 			root: "testdata",
 			cfg: config{
-				ext:  ".log",
+				exts: []string{".log"},
 				size: 10,
 				list: true,
 			},
@@ -50,7 +51,7 @@ func TestRun(t *testing.T) {
 			name: "FilterExtensionSizeNoMatch",
 			root: "testdata",
 			cfg: config{
-				ext:  ".log",
+				exts: []string{".log"},
 				size: 20,
 				list: true,
 			},
@@ -60,7 +61,7 @@ func TestRun(t *testing.T) {
 			name: "FilterExtensionNoMatch",
 			root: "testdata",
 			cfg: config{
-				ext:  ".g",
+				exts: []string{".g"},
 				size: 0,
 				list: true,
 			},
@@ -85,6 +86,45 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRunExclude(t *testing.T) {
+	tempDir := t.TempDir()
+
+	for _, fname := range []string{
+		".git/objects/pack.log",
+		"node_modules/pkg/lib/debug.log",
+		"src/app.log",
+		"src/node_modules.log",
+	} {
+		fpath := filepath.Join(tempDir, fname)
+		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(fpath, []byte("dummy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buffer bytes.Buffer
+
+	cfg := config{
+		exts:     []string{".log"},
+		excludes: []string{".git", "node_*"},
+		list:     true,
+	}
+
+	if err := run(tempDir, &buffer, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := filepath.Join(tempDir, "src", "app.log") + "\n" +
+		filepath.Join(tempDir, "src", "node_modules.log") + "\n"
+
+	if res := buffer.String(); expected != res {
+		t.Errorf("Expected %q, got %q instead\n", expected, res)
+	}
+}
+
 func TestRunDeleteExtension(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -96,19 +136,19 @@ func TestRunDeleteExtension(t *testing.T) {
 	}{
 		{
 			name:        "DeleteExtensionNoMatch",
-			cfg:         config{ext: ".log", delete: true},
+			cfg:         config{exts: []string{".log"}, delete: true},
 			extNoDelete: ".gz", nDelete: 0, nNoDelete: 10,
 			expected: "",
 		},
 		{
 			name:        "DeleteExtensionMatch",
-			cfg:         config{ext: ".log", delete: true},
+			cfg:         config{exts: []string{".log"}, delete: true},
 			extNoDelete: "", nDelete: 10, nNoDelete: 0,
 			expected: "",
 		},
 		{
 			name:        "DeleteExtensionMixed",
-			cfg:         config{ext: ".log", delete: true},
+			cfg:         config{exts: []string{".log"}, delete: true},
 			extNoDelete: ".gz", nDelete: 5, nNoDelete: 5,
 			expected: "",
 		},
@@ -123,7 +163,7 @@ func TestRunDeleteExtension(t *testing.T) {
 			tc.cfg.wLog = &logBuffer
 
 			tempDir, cleanup := createTempDir(t, map[string]int{
-				tc.cfg.ext:     tc.nDelete,
+				tc.cfg.exts[0]: tc.nDelete,
 				tc.extNoDelete: tc.nNoDelete,
 			})
 			defer cleanup()
@@ -169,17 +209,17 @@ func TestRunArchive(t *testing.T) {
 	}{
 		{
 			name:         "ArchiveExtensionNoMatch",
-			cfg:          config{ext: ".log"},
+			cfg:          config{exts: []string{".log"}},
 			extNoArchive: ".gz", nArchive: 0, nNoArchive: 10,
 		},
 		{
 			name:         "ArchiveExtensionMatch",
-			cfg:          config{ext: ".log"},
+			cfg:          config{exts: []string{".log"}},
 			extNoArchive: "", nArchive: 10, nNoArchive: 0,
 		},
 		{
 			name:         "ArchiveExtensionMixed",
-			cfg:          config{ext: ".log"},
+			cfg:          config{exts: []string{".log"}},
 			extNoArchive: ".gz", nArchive: 5, nNoArchive: 5,
 		},
 	}
@@ -189,7 +229,7 @@ func TestRunArchive(t *testing.T) {
 			var buffer bytes.Buffer
 
 			tempDir, cleanup := createTempDir(t, map[string]int{
-				tc.cfg.ext:      tc.nArchive,
+				tc.cfg.exts[0]:  tc.nArchive,
 				tc.extNoArchive: tc.nNoArchive,
 			})
 			defer cleanup()
@@ -203,7 +243,7 @@ func TestRunArchive(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			pattern := filepath.Join(tempDir, fmt.Sprintf("*%s", tc.cfg.ext))
+			pattern := filepath.Join(tempDir, fmt.Sprintf("*%s", tc.cfg.exts[0]))
 			expFiles, err := filepath.Glob(pattern)
 			if err != nil {
 				t.Fatal(err)
@@ -252,3 +292,85 @@ func createTempDir(t *testing.T, files map[string]int) (dirname string, cleanup
 
 	return tempDir, func() { os.RemoveAll(tempDir) }
 }
+
+func TestRunDryRun(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 2, ".gz": 1})
+	defer cleanup()
+
+	var buffer, logBuffer bytes.Buffer
+
+	cfg := config{exts: []string{".log"}, delete: true, dryRun: true, wLog: &logBuffer}
+
+	if err := run(tempDir, &buffer, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fmt.Sprintf("would delete %s (5 bytes)\nwould delete %s (5 bytes)\n"+
+		"would delete 2 files (10 bytes), freeing space\n",
+		filepath.Join(tempDir, "file1.log"), filepath.Join(tempDir, "file2.log"))
+
+	if res := buffer.String(); expected != res {
+		t.Errorf("Expected %q, got %q instead\n", expected, res)
+	}
+
+	filesLeft, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(filesLeft) != 3 {
+		t.Errorf("Expected 3 files left, got %d instead\n", len(filesLeft))
+	}
+
+	if logBuffer.Len() != 0 {
+		t.Errorf("Expected no log, got %q instead\n", logBuffer.String())
+	}
+}
+
+func TestRunConfirm(t *testing.T) {
+	testCases := []struct {
+		name    string
+		answer  string
+		expErr  error
+		nDelete int
+	}{
+		{"Yes", "y\n", nil, 2},
+		{"No", "n\n", ErrAborted, 0},
+		{"Empty", "", ErrAborted, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir, cleanup := createTempDir(t, map[string]int{".log": 2})
+			defer cleanup()
+
+			var buffer bytes.Buffer
+
+			cfg := config{
+				exts:    []string{".log"},
+				delete:  true,
+				confirm: true,
+				in:      strings.NewReader(tc.answer),
+				wLog:    io.Discard,
+			}
+
+			if err := run(tempDir, &buffer, cfg); !errors.Is(err, tc.expErr) {
+				t.Fatalf("Expected error %v, got %v instead\n", tc.expErr, err)
+			}
+
+			expPrompt := "Delete 2 files (10 bytes)? [y/N] "
+			if res := buffer.String(); expPrompt != res {
+				t.Errorf("Expected %q, got %q instead\n", expPrompt, res)
+			}
+
+			filesLeft, err := os.ReadDir(tempDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(filesLeft) != 2-tc.nDelete {
+				t.Errorf("Expected %d files left, got %d instead\n", 2-tc.nDelete, len(filesLeft))
+			}
+		})
+	}
+}
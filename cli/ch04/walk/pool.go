@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// match is a file selected by the filters, numbered in walk order.
+type match struct {
+	idx  int
+	path string
+	size int64
+}
+
+// walk sends the files under root selected by cfg, in lexical order, to the
+// returned channel, closing it when done. Errors reading the tree do not
+// stop the walk; they are added to errs, which is safe to read once the
+// channel is closed.
+func walk(root string, cfg config, errs *[]error) <-chan match {
+	matches := make(chan match)
+
+	// Keeps the walk away from its own output when it is under root: the
+	// bundle being written, the trash and the log.
+	outputs := []string{cfg.trash, cfg.logName}
+	if cfg.archiveFormat != "" {
+		outputs = append(outputs, cfg.archive)
+	}
+
+	own := map[string]bool{}
+	for _, name := range outputs {
+		if abs, err := filepath.Abs(name); name != "" && err == nil {
+			own[abs] = true
+		}
+	}
+
+	go func() {
+		defer close(matches)
+
+		idx := 0
+
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				*errs = append(*errs, err)
+				return nil
+			}
+
+			abs, _ := filepath.Abs(path)
+
+			if d.IsDir() {
+				if path != root && (excludeDir(d.Name(), cfg.excludes) || own[abs]) {
+					return fs.SkipDir
+				}
+
+				return nil
+			}
+
+			if own[abs] {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				*errs = append(*errs, err)
+				return nil
+			}
+
+			if filterOut(path, info, cfg) {
+				return nil
+			}
+
+			matches <- match{idx, path, info.Size()}
+			idx++
+
+			return nil
+		})
+	}()
+
+	return matches
+}
+
+func collect(matches <-chan match) []match {
+	all := []match{}
+	for m := range matches {
+		all = append(all, m)
+	}
+
+	return all
+}
+
+func feed(all []match) <-chan match {
+	matches := make(chan match, len(all))
+	for _, m := range all {
+		matches <- m
+	}
+	close(matches)
+
+	return matches
+}
+
+// result holds what processing a file printed and logged, to be written
+// out in walk order.
+type result struct {
+	idx int
+	out bytes.Buffer
+	log bytes.Buffer
+	err error
+}
+
+// runPool runs fn on every match with a pool of workers. The output and
+// log lines of each file are written to out and wLog in walk order, so
+// they never interleave, and every error is returned rather than just the
+// first one.
+func runPool(matches <-chan match, workers int, out, wLog io.Writer, fn func(match, *result) error) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make(chan *result)
+	wg := sync.WaitGroup{}
+
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for m := range matches {
+				r := &result{idx: m.idx}
+				r.err = fn(m, r)
+				results <- r
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	errs := []error{}
+	pending := map[int]*result{}
+	next := 0
+
+	for r := range results {
+		pending[r.idx] = r
+
+		for r, ok := pending[next]; ok; r, ok = pending[next] {
+			delete(pending, next)
+			next++
+
+			if _, err := out.Write(r.out.Bytes()); err != nil {
+				errs = append(errs, err)
+			}
+
+			if r.log.Len() > 0 {
+				if _, err := wLog.Write(r.log.Bytes()); err != nil {
+					errs = append(errs, err)
+				}
+			}
+
+			if r.err != nil {
+				errs = append(errs, r.err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// lockedBundle lets the workers add files to a bundle one at a time.
+type lockedBundle struct {
+	mu sync.Mutex
+	bundle
+}
+
+func (b *lockedBundle) add(path, name string, info fs.FileInfo) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.bundle.add(path, name, info)
+}
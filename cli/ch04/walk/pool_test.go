@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunPoolOrder(t *testing.T) {
+	all := []match{}
+	for i := range 50 {
+		all = append(all, match{idx: i, path: fmt.Sprintf("file%02d", i)})
+	}
+
+	var (
+		out, logBuffer bytes.Buffer
+		running, peak  atomic.Int32
+	)
+
+	err := runPool(feed(all), 4, &out, &logBuffer, func(m match, r *result) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+
+		time.Sleep(time.Duration(rand.IntN(2000)) * time.Microsecond)
+
+		fmt.Fprintf(&r.log, "start %s\n", m.path)
+		fmt.Fprintf(&r.out, "%s\n", m.path)
+		fmt.Fprintf(&r.log, "end %s\n", m.path)
+
+		if m.idx%10 == 0 {
+			return fmt.Errorf("failed %s", m.path)
+		}
+
+		return nil
+	})
+
+	expOut, expLog := "", ""
+	for _, m := range all {
+		expOut += m.path + "\n"
+		expLog += fmt.Sprintf("start %s\nend %s\n", m.path, m.path)
+	}
+
+	if out.String() != expOut {
+		t.Errorf("Expected output in walk order, got %q instead\n", out.String())
+	}
+
+	if logBuffer.String() != expLog {
+		t.Errorf("Expected log lines in walk order, got %q instead\n", logBuffer.String())
+	}
+
+	if p := peak.Load(); p > 4 {
+		t.Errorf("Expected at most 4 workers, got %d instead\n", p)
+	}
+
+	expErr := "failed file00\nfailed file10\nfailed file20\nfailed file30\nfailed file40"
+	if err == nil || err.Error() != expErr {
+		t.Errorf("Expected errors %q, got %v instead\n", expErr, err)
+	}
+}
+
+func TestRunJobs(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 30})
+	defer cleanup()
+
+	var buffer, logBuffer bytes.Buffer
+
+	cfg := config{exts: []string{".log"}, delete: true, jobs: 8, wLog: &logBuffer}
+
+	if err := run(tempDir, &buffer, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	expFiles, err := filepath.Glob(filepath.Join(tempDir, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(expFiles) != 0 {
+		t.Errorf("Expected all files deleted, got %d left instead\n", len(expFiles))
+	}
+
+	paths := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(logBuffer.String()), "\n") {
+		fields := strings.Fields(line)
+		paths = append(paths, fields[len(fields)-1])
+	}
+
+	sorted := append([]string{}, paths...)
+	slices.Sort(sorted)
+
+	if len(paths) != 30 || !slices.Equal(paths, sorted) {
+		t.Errorf("Expected 30 deletes logged in walk order, got %q instead\n", paths)
+	}
+}
+
+func TestRunAggregateErrors(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 3})
+	defer cleanup()
+
+	var buffer bytes.Buffer
+
+	cfg := config{
+		exts:    []string{".log"},
+		archive: filepath.Join(tempDir, "missing"),
+		delete:  true,
+		jobs:    2,
+	}
+
+	err := run(tempDir, &buffer, cfg)
+	if err == nil {
+		t.Fatal("Expected an error, got nil instead")
+	}
+
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		t.Fatalf("Expected joined errors, got %v instead\n", err)
+	}
+
+	if n := len(joined.Unwrap()); n != 3 {
+		t.Errorf("Expected an error per file, got %d instead: %v\n", n, err)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected error %v, got %v instead\n", os.ErrNotExist, err)
+	}
+
+	filesLeft, err := filepath.Glob(filepath.Join(tempDir, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(filesLeft) != 3 {
+		t.Errorf("Expected files that failed to archive to be kept, got %d left instead\n", len(filesLeft))
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrRestore       = errors.New("Cannot restore some files")
+	ErrRestoreExists = errors.New("A file already exists at the original path")
+)
+
+const (
+	trashLogPrefix = "TRASHED FILE: "
+	// logArrow separates a file from where it was moved or linked to in
+	// the delete log.
+	logArrow = " => "
+)
+
+// trashFile moves path into the files directory of trashDir, writing its
+// original location and deletion date next to it in the info directory,
+// like the freedesktop.org trash does. The move is logged so restore can
+// put the file back.
+func trashFile(path, trashDir string, trashLogger *log.Logger) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	trashDir, err = filepath.Abs(trashDir)
+	if err != nil {
+		return err
+	}
+
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+
+	name, info, err := createTrashInfo(infoDir, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(info, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: abs}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+
+	if err := info.Close(); err != nil {
+		return err
+	}
+
+	target := filepath.Join(filesDir, name)
+
+	if err := moveFile(path, target); err != nil {
+		os.Remove(info.Name())
+		return err
+	}
+
+	trashLogger.Println(abs + logArrow + target)
+	return nil
+}
+
+// createTrashInfo creates the info file of a trashed file, picking a name
+// not used by other files in the trash.
+func createTrashInfo(infoDir, base string) (string, *os.File, error) {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, i, ext)
+		}
+
+		f, err := os.OpenFile(filepath.Join(infoDir, name+".trashinfo"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		return name, f, nil
+	}
+}
+
+// moveFile renames src to dest, copying it when they are on different file
+// systems. Copies keep the mode and modification time.
+func moveFile(src, dest string) error {
+	err := os.Rename(src, dest)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(dest)
+		return err
+	}
+
+	if err := os.Chtimes(dest, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+// trashEntry is a file moved to the trash, as recorded in the delete log.
+type trashEntry struct {
+	orig  string
+	trash string
+}
+
+// parseTrashLog returns the trashed files recorded in a delete log,
+// ignoring every other line.
+func parseTrashLog(r io.Reader) ([]trashEntry, error) {
+	entries := []trashEntry{}
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		rest, ok := strings.CutPrefix(s.Text(), trashLogPrefix)
+		if !ok {
+			continue
+		}
+
+		// Skips the date and time written by the logger.
+		fields := strings.SplitN(rest, " ", 3)
+		if len(fields) < 3 {
+			continue
+		}
+
+		orig, trash, ok := strings.Cut(fields[2], logArrow)
+		if !ok {
+			continue
+		}
+
+		entries = append(entries, trashEntry{orig, trash})
+	}
+
+	return entries, s.Err()
+}
+
+// restore moves the files recorded in the delete log read from r back from
+// the trash, printing every file restored to out. Files that are no longer
+// in the trash, because they were already restored, are skipped, and files
+// whose original path is taken again are left in the trash.
+func restore(r io.Reader, out, errOut io.Writer) error {
+	entries, err := parseTrashLog(r)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+
+	for _, e := range entries {
+		if _, err := os.Lstat(e.trash); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err := restoreFile(e); err != nil {
+			fmt.Fprintf(errOut, "walk: %s: %v\n", e.orig, err)
+			failed = true
+			continue
+		}
+
+		fmt.Fprintln(out, e.orig)
+	}
+
+	if failed {
+		return ErrRestore
+	}
+
+	return nil
+}
+
+func restoreFile(e trashEntry) error {
+	if _, err := os.Lstat(e.orig); err == nil {
+		return ErrRestoreExists
+	}
+
+	if err := os.MkdirAll(filepath.Dir(e.orig), 0755); err != nil {
+		return err
+	}
+
+	if err := moveFile(e.trash, e.orig); err != nil {
+		return err
+	}
+
+	infoFile := filepath.Join(filepath.Dir(filepath.Dir(e.trash)), "info", filepath.Base(e.trash)+".trashinfo")
+	if err := os.Remove(infoFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrashRestore(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 2, ".gz": 1})
+	defer cleanup()
+
+	trashDir := t.TempDir()
+
+	// A file of the same name already in the trash.
+	if err := os.MkdirAll(filepath.Join(trashDir, "info"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(trashDir, "info", "file1.log.trashinfo"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer, logBuffer bytes.Buffer
+
+	cfg := config{exts: []string{".log"}, delete: true, trash: trashDir, wLog: &logBuffer}
+
+	if err := run(tempDir, &buffer, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fname := range []string{"files/file1.2.log", "files/file2.log", "info/file1.2.log.trashinfo"} {
+		if _, err := os.Stat(filepath.Join(trashDir, fname)); err != nil {
+			t.Errorf("Expected %s in the trash: %v\n", fname, err)
+		}
+	}
+
+	info, err := os.ReadFile(filepath.Join(trashDir, "info", "file2.log.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(info), "Path="+filepath.ToSlash(filepath.Join(tempDir, "file2.log"))) {
+		t.Errorf("Expected the original path in the trash info, got %q\n", info)
+	}
+
+	if filesLeft, _ := os.ReadDir(tempDir); len(filesLeft) != 1 {
+		t.Fatalf("Expected 1 file left, got %d instead\n", len(filesLeft))
+	}
+
+	// The second restore finds nothing left in the trash.
+	for _, expRestored := range []int{2, 0} {
+		var out, errOut bytes.Buffer
+
+		if err := restore(strings.NewReader(logBuffer.String()), &out, &errOut); err != nil {
+			t.Fatal(err, errOut.String())
+		}
+
+		if n := strings.Count(out.String(), "\n"); n != expRestored {
+			t.Errorf("Expected %d files restored, got %d instead\n", expRestored, n)
+		}
+	}
+
+	if filesLeft, _ := os.ReadDir(tempDir); len(filesLeft) != 3 {
+		t.Errorf("Expected 3 files restored, got %d instead\n", len(filesLeft))
+	}
+
+	if _, err := os.Stat(filepath.Join(trashDir, "info", "file2.log.trashinfo")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the trash info to be removed, got %v\n", err)
+	}
+}
+
+func TestTrashInsideRoot(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 2})
+	defer cleanup()
+
+	trashDir := filepath.Join(tempDir, "trash")
+	logName := filepath.Join(tempDir, "walk.log")
+
+	if err := os.WriteFile(logName, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer, logBuffer bytes.Buffer
+
+	cfg := config{exts: []string{".log"}, delete: true, trash: trashDir, wLog: &logBuffer, logName: logName}
+
+	// The second run finds the trashed files and the log out of its way.
+	for _, expTrashed := range []int{2, 0} {
+		logBuffer.Reset()
+
+		if err := run(tempDir, &buffer, cfg); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := strings.Count(logBuffer.String(), "\n"); n != expTrashed {
+			t.Errorf("Expected %d files trashed, got %q instead\n", expTrashed, logBuffer.String())
+		}
+	}
+
+	if _, err := os.Stat(logName); err != nil {
+		t.Errorf("Expected the log file to be kept, got %v instead\n", err)
+	}
+
+	if trashed, _ := os.ReadDir(filepath.Join(trashDir, "files")); len(trashed) != 2 {
+		t.Errorf("Expected 2 files in the trash, got %d instead\n", len(trashed))
+	}
+}
+
+func TestRestoreExisting(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 1})
+	defer cleanup()
+
+	var logBuffer bytes.Buffer
+
+	cfg := config{exts: []string{".log"}, delete: true, trash: t.TempDir(), wLog: &logBuffer}
+
+	if err := run(tempDir, &bytes.Buffer{}, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	fpath := filepath.Join(tempDir, "file1.log")
+	if err := os.WriteFile(fpath, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+
+	if err := restore(&logBuffer, &out, &errOut); !errors.Is(err, ErrRestore) {
+		t.Fatalf("Expected error %v, got %v instead\n", ErrRestore, err)
+	}
+
+	if !strings.Contains(errOut.String(), ErrRestoreExists.Error()) {
+		t.Errorf("Expected %q, got %q instead\n", ErrRestoreExists, errOut.String())
+	}
+
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "new" {
+		t.Errorf("Expected the existing file to be kept, got %q instead\n", data)
+	}
+}